@@ -0,0 +1,167 @@
+package sec4dev
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// AdaptiveConfig configures adaptive concurrency. Zero fields take their
+// defaults.
+type AdaptiveConfig struct {
+	// Min and Max bound the concurrency limit (defaults: 1 and 32).
+	Min int
+	Max int
+	// Initial is the starting limit (default: 4).
+	Initial int
+	// LatencyTolerance backs off when an HTTP round trip takes longer than
+	// this multiple of the best recent latency for the same path (default: 2).
+	LatencyTolerance float64
+	// MinRemaining backs off when X-RateLimit-Remaining falls below this
+	// fraction of X-RateLimit-Limit (default: 0.05).
+	MinRemaining float64
+}
+
+// WithAdaptiveConcurrency replaces the fixed batch concurrency with a limit
+// that grows by one per round trip while attempts succeed quickly and halves
+// on 429s, 5xx and network errors, slow attempts, or a nearly exhausted
+// quota. It applies to CheckBatch and to callers of Client.Adaptive.
+func WithAdaptiveConcurrency(cfg AdaptiveConfig) ClientOption {
+	return func(c *Client) {
+		if cfg.Min <= 0 {
+			cfg.Min = 1
+		}
+		if cfg.Max <= 0 {
+			cfg.Max = 32
+		}
+		if cfg.Max < cfg.Min {
+			cfg.Max = cfg.Min
+		}
+		if cfg.Initial <= 0 {
+			cfg.Initial = defaultBatchConcurrency
+		}
+		if cfg.LatencyTolerance <= 1 {
+			cfg.LatencyTolerance = 2
+		}
+		if cfg.MinRemaining <= 0 {
+			cfg.MinRemaining = 0.05
+		}
+		c.adaptive = newAdaptiveLimiter(cfg)
+	}
+}
+
+// AdaptiveLimiter is a concurrency limit tuned from the responses the client
+// observes. A nil *AdaptiveLimiter never limits.
+type AdaptiveLimiter struct {
+	mu           sync.Mutex
+	cfg          AdaptiveConfig
+	limit        float64
+	inFlight     int
+	wake         chan struct{}
+	baselines    map[string]time.Duration // by request path
+	lastDecrease time.Time
+}
+
+func newAdaptiveLimiter(cfg AdaptiveConfig) *AdaptiveLimiter {
+	limit := math.Max(float64(cfg.Min), math.Min(float64(cfg.Max), float64(cfg.Initial)))
+	return &AdaptiveLimiter{cfg: cfg, limit: limit, wake: make(chan struct{}), baselines: make(map[string]time.Duration)}
+}
+
+// Adaptive returns the client's adaptive limiter, or nil if
+// WithAdaptiveConcurrency was not used.
+func (c *Client) Adaptive() *AdaptiveLimiter {
+	return c.adaptive
+}
+
+// Acquire waits until fewer than Limit operations hold a slot. The returned
+// function releases the slot.
+func (a *AdaptiveLimiter) Acquire(ctx context.Context) (func(), error) {
+	if a == nil {
+		return func() {}, nil
+	}
+	for {
+		a.mu.Lock()
+		if a.inFlight < int(a.limit) {
+			a.inFlight++
+			a.mu.Unlock()
+			return a.release, nil
+		}
+		wake := a.wake
+		a.mu.Unlock()
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Limit returns the current concurrency limit.
+func (a *AdaptiveLimiter) Limit() int {
+	if a == nil {
+		return 0
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return int(a.limit)
+}
+
+// max returns the most workers the limit can ever allow.
+func (a *AdaptiveLimiter) max() int {
+	return a.cfg.Max
+}
+
+func (a *AdaptiveLimiter) release() {
+	a.mu.Lock()
+	a.inFlight--
+	a.notify()
+	a.mu.Unlock()
+}
+
+// notify wakes waiters. The caller holds a.mu.
+func (a *AdaptiveLimiter) notify() {
+	close(a.wake)
+	a.wake = make(chan struct{})
+}
+
+// observe adjusts the limit after one HTTP round trip to path. status is 0
+// for network errors. Latency excludes time queued in the bulkhead and rate
+// limiter, and is compared with the baseline for the same path, since a batch
+// call is always slower than a single check.
+func (a *AdaptiveLimiter) observe(path string, status int, latency time.Duration, rl RateLimitInfo) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	if status > 0 && status < 500 && status != 429 {
+		// The baseline tracks the best recent latency, drifting up slowly so
+		// it follows lasting changes.
+		if b := a.baselines[path]; b == 0 || latency < b {
+			a.baselines[path] = latency
+		} else {
+			a.baselines[path] = b + (latency-b)/100
+		}
+	}
+
+	backOff := status == 0 || status == 429 || status >= 500 ||
+		rl.Limit > 0 && float64(rl.Remaining) < a.cfg.MinRemaining*float64(rl.Limit) ||
+		float64(latency) > a.cfg.LatencyTolerance*float64(a.baselines[path])
+	if backOff {
+		// Decrease at most once per round trip, so a burst of failures from
+		// requests already in flight counts once.
+		if now.Sub(a.lastDecrease) < latency {
+			return
+		}
+		a.lastDecrease = now
+		a.limit = math.Max(float64(a.cfg.Min), math.Floor(a.limit/2))
+		return
+	}
+	before := int(a.limit)
+	a.limit = math.Min(float64(a.cfg.Max), a.limit+1/a.limit)
+	if int(a.limit) > before {
+		a.notify()
+	}
+}
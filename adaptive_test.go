@@ -0,0 +1,181 @@
+package sec4dev
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveLimiter_IncreasesAndBacksOff(t *testing.T) {
+	a := newAdaptiveLimiter(AdaptiveConfig{Min: 1, Max: 8, Initial: 2, LatencyTolerance: 2, MinRemaining: 0.05})
+	for i := 0; i < 20; i++ {
+		a.observe("/email/check", 200, time.Millisecond, RateLimitInfo{})
+	}
+	if got := a.Limit(); got <= 2 {
+		t.Fatalf("limit after fast successes = %d, want > 2", got)
+	}
+
+	before := a.Limit()
+	a.observe("/email/check", 429, time.Millisecond, RateLimitInfo{})
+	if got := a.Limit(); got != before/2 {
+		t.Errorf("limit after 429 = %d, want %d", got, before/2)
+	}
+
+	// A second failure within the same round trip does not halve again.
+	before = a.Limit()
+	a.observe("/email/check", 503, time.Hour, RateLimitInfo{})
+	if got := a.Limit(); got != before {
+		t.Errorf("limit after a burst of failures = %d, want %d", got, before)
+	}
+
+	b := newAdaptiveLimiter(AdaptiveConfig{Min: 1, Max: 8, Initial: 8, LatencyTolerance: 2, MinRemaining: 0.05})
+	b.observe("/email/check", 200, time.Millisecond, RateLimitInfo{Limit: 1000, Remaining: 10})
+	if got := b.Limit(); got != 4 {
+		t.Errorf("limit with quota nearly exhausted = %d, want 4", got)
+	}
+	c := newAdaptiveLimiter(AdaptiveConfig{Min: 1, Max: 8, Initial: 8, LatencyTolerance: 2, MinRemaining: 0.05})
+	c.observe("/email/check", 200, time.Millisecond, RateLimitInfo{})
+	c.observe("/email/check", 200, 10*time.Millisecond, RateLimitInfo{})
+	if got := c.Limit(); got != 4 {
+		t.Errorf("limit after a slow attempt = %d, want 4", got)
+	}
+}
+
+func TestAdaptiveLimiter_BaselinePerPath(t *testing.T) {
+	a := newAdaptiveLimiter(AdaptiveConfig{Min: 1, Max: 8, Initial: 4, LatencyTolerance: 2, MinRemaining: 0.05})
+	a.observe("/email/check", 200, time.Millisecond, RateLimitInfo{})
+	a.observe("/email/check/batch", 200, 50*time.Millisecond, RateLimitInfo{})
+	a.observe("/email/check/batch", 200, 60*time.Millisecond, RateLimitInfo{})
+	if got := a.Limit(); got < 4 {
+		t.Errorf("limit = %d, want batch calls judged against their own baseline", got)
+	}
+	a.observe("/email/check", 200, 10*time.Millisecond, RateLimitInfo{})
+	if got := a.Limit(); got >= 4 {
+		t.Errorf("limit = %d, want a slow single check to back off", got)
+	}
+}
+
+func TestAdaptiveConcurrency_IgnoresQueueTime(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+		emailHandler(w, r)
+	}))
+	defer server.Close()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(0), WithBulkhead(1, 8, 0),
+		WithAdaptiveConcurrency(AdaptiveConfig{Min: 1, Max: 8, Initial: 4, LatencyTolerance: 3}))
+
+	// Requests queue behind each other in the bulkhead; the last waits
+	// about 90ms before its 30ms round trip starts.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Email().Check(context.Background(), "user@gmail.com"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if l := client.Adaptive().Limit(); l < 4 {
+		t.Errorf("limit = %d, want no back-off for time spent queued", l)
+	}
+}
+
+func TestAdaptiveLimiter_AcquireBlocks(t *testing.T) {
+	a := newAdaptiveLimiter(AdaptiveConfig{Min: 1, Max: 1, Initial: 1, LatencyTolerance: 2, MinRemaining: 0.05})
+	release, err := a.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := a.Acquire(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want DeadlineExceeded", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		r, err := a.Acquire(context.Background())
+		if err == nil {
+			r()
+		}
+		close(done)
+	}()
+	release()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Acquire not woken by release")
+	}
+
+	var nilLimiter *AdaptiveLimiter
+	if _, err := nilLimiter.Acquire(context.Background()); err != nil {
+		t.Errorf("nil limiter: %v", err)
+	}
+}
+
+func TestCheckBatch_AdaptiveConcurrency(t *testing.T) {
+	var inFlight, peak, calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/ip/check/batch" {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"detail": "Not Found"})
+			return
+		}
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if atomic.AddInt32(&calls, 1) == 10 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]string{"detail": "Too Many Requests"})
+			return
+		}
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ip": body["ip"], "classification": "residential", "confidence": 0.9,
+			"signals": map[string]bool{"is_residential": true},
+		})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL+"/api/v1"), WithHTTPClient(server.Client()),
+		WithRetries(0), WithAdaptiveConcurrency(AdaptiveConfig{Min: 1, Max: 4, Initial: 2}))
+	inputs := make([]string, 30)
+	for i := range inputs {
+		inputs[i] = fmt.Sprintf("203.0.113.%d", i+1)
+	}
+	out, err := client.IP().CheckBatch(context.Background(), inputs)
+	if err != nil {
+		t.Fatalf("CheckBatch: %v", err)
+	}
+	failed := 0
+	for _, r := range out {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Errorf("failed = %d, want the single 429", failed)
+	}
+	if p := atomic.LoadInt32(&peak); p > 4 {
+		t.Errorf("peak concurrency = %d, want at most Max 4", p)
+	}
+	if l := client.Adaptive().Limit(); l < 1 || l > 4 {
+		t.Errorf("limit = %d, want within [1, 4]", l)
+	}
+}
@@ -0,0 +1,384 @@
+package sec4dev
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultBatchSize        = 100
+	defaultBatchConcurrency = 4
+)
+
+// EmailBatchResult is the outcome for one input of EmailService.CheckBatch.
+// Exactly one of Result and Err is set.
+type EmailBatchResult struct {
+	Input  string
+	Result *EmailCheckResult
+	Err    error
+}
+
+// IPBatchResult is the outcome for one input of IPService.CheckBatch.
+// Exactly one of Result and Err is set.
+type IPBatchResult struct {
+	Input  string
+	Result *IPCheckResult
+	Err    error
+}
+
+// WithBatchSize sets how many inputs CheckBatch sends per request
+// (default: 100).
+func WithBatchSize(n int) ClientOption {
+	return func(c *Client) {
+		c.batchSize = n
+	}
+}
+
+// WithBatchConcurrency sets how many batch requests, or single checks when
+// the batch endpoint is unavailable, CheckBatch runs at once (default: 4).
+func WithBatchConcurrency(n int) ClientOption {
+	return func(c *Client) {
+		c.batchConcurrency = n
+	}
+}
+
+// batchSpec describes one check endpoint for batching.
+type batchSpec struct {
+	path     string
+	field    string
+	validate func(string) error
+	override func(string) (interface{}, bool)
+	decode   func([]byte) (interface{}, error)
+	single   func(ctx context.Context, input string) (interface{}, error)
+	// pack builds the []EmailBatchResult or []IPBatchResult that
+	// interceptors see as a batch response's Result.
+	pack func(inputs []string, results []interface{}, errs []error) interface{}
+}
+
+// batchItem is one entry of a batch response: either a result or an error.
+type batchItem struct {
+	Error *struct {
+		Status int    `json:"status"`
+		Detail string `json:"detail"`
+	} `json:"error"`
+}
+
+// checkBatch validates inputs, answers what it can from the cache, and sends
+// the rest to spec.path+"/batch" in chunks. If the server has no batch route
+// (404), it falls back to single checks from then on, run in one pass across
+// all chunks so they share the batch concurrency. The returned
+// error is the first error that failed a whole chunk. Batches run at
+// background priority unless ctx sets one.
+func (c *Client) checkBatch(ctx context.Context, spec batchSpec, inputs []string) ([]interface{}, []error, error) {
+	ctx = defaultPriority(ctx, PriorityBackground)
+	results := make([]interface{}, len(inputs))
+	errs := make([]error, len(inputs))
+	var pending []int
+	for i, in := range inputs {
+		if err := spec.validate(in); err != nil {
+			errs[i] = err
+			continue
+		}
+		if r, ok := spec.override(in); ok {
+			results[i] = r
+			continue
+		}
+		key := &Request{Path: spec.path, Body: map[string]string{spec.field: strings.TrimSpace(in)}}
+		if resp, ok := c.cached(key, spec.decode); ok {
+			results[i] = resp.Result
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	size := c.batchSize
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+	var chunks [][]int
+	for len(pending) > 0 {
+		n := size
+		if n > len(pending) {
+			n = len(pending)
+		}
+		chunks = append(chunks, pending[:n])
+		pending = pending[n:]
+	}
+
+	var mu sync.Mutex
+	var firstErr error
+	var single []int
+	c.forEach(ctx, len(chunks), func(n int) {
+		chunk := chunks[n]
+		fallback, err := c.sendBatchChunk(ctx, spec, inputs, chunk, results, errs)
+		if fallback {
+			mu.Lock()
+			single = append(single, chunk...)
+			mu.Unlock()
+		} else if err != nil {
+			for _, i := range chunk {
+				errs[i] = err
+			}
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = err
+			}
+			mu.Unlock()
+		}
+	})
+	c.forEach(ctx, len(single), func(n int) {
+		i := single[n]
+		release, err := c.adaptive.Acquire(ctx)
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		results[i], errs[i] = spec.single(ctx, inputs[i])
+		release()
+	})
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+	return results, errs, firstErr
+}
+
+// sendBatchChunk checks the inputs at indexes chunk, writing to results and
+// errs. It reports fallback when the chunk needs single checks instead, and
+// returns an error only when the whole chunk failed.
+func (c *Client) sendBatchChunk(ctx context.Context, spec batchSpec, inputs []string, chunk []int, results []interface{}, errs []error) (fallback bool, err error) {
+	if !c.batchUnsupported(spec.path) {
+		values := make([]string, len(chunk))
+		for n, i := range chunk {
+			values[n] = strings.TrimSpace(inputs[i])
+		}
+		req := &Request{
+			Method:  http.MethodPost,
+			Path:    spec.path + "/batch",
+			Body:    map[string][]string{spec.field + "s": values},
+			noCache: true,
+		}
+		release, err := c.adaptive.Acquire(ctx)
+		if err != nil {
+			return false, err
+		}
+		resp, err := c.call(ctx, req, decodeBatchItems(spec, values))
+		release()
+		if _, ok := err.(*NotFoundError); ok {
+			c.markBatchUnsupported(spec.path)
+		} else if err != nil {
+			return false, err
+		} else {
+			out, outErrs, ok := unpackBatch(resp.Result)
+			if !ok || len(out) != len(chunk) {
+				return false, baseError(fmt.Sprintf("Unexpected batch result %T", resp.Result), resp.StatusCode, nil)
+			}
+			for n, i := range chunk {
+				results[i], errs[i] = out[n], outErrs[n]
+				if errs[i] == nil && out[n] != nil {
+					b, _ := json.Marshal(results[i])
+					c.store(&Request{Path: spec.path, Body: map[string]string{spec.field: values[n]}}, b)
+				}
+			}
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// decodeBatchItems decodes {"results": [...]} holding one entry per input in
+// request order, each a check result or {"error": {"status": ..., "detail":
+// ...}}, and packs them with spec.pack.
+func decodeBatchItems(spec batchSpec, inputs []string) func([]byte) (interface{}, error) {
+	want := len(inputs)
+	return func(out []byte) (interface{}, error) {
+		var raw struct {
+			Results []json.RawMessage `json:"results"`
+		}
+		if err := json.Unmarshal(out, &raw); err != nil {
+			return nil, err
+		}
+		if len(raw.Results) != want {
+			return nil, baseError(fmt.Sprintf("Batch response has %d results for %d inputs", len(raw.Results), want), 200, nil)
+		}
+		results, errs := make([]interface{}, want), make([]error, want)
+		for n, item := range raw.Results {
+			var bi batchItem
+			if err := json.Unmarshal(item, &bi); err != nil {
+				errs[n] = err
+				continue
+			}
+			if bi.Error != nil {
+				errs[n] = errFromStatus(bi.Error.Status, bi.Error.Detail, nil, 0, 0, 0)
+				continue
+			}
+			results[n], errs[n] = spec.decode(item)
+		}
+		return spec.pack(inputs, results, errs), nil
+	}
+}
+
+// unpackBatch splits a batch response's Result, as left by the interceptor
+// chain, back into results and errors. Missing results are nil.
+func unpackBatch(v interface{}) ([]interface{}, []error, bool) {
+	var results []interface{}
+	var errs []error
+	switch out := v.(type) {
+	case []EmailBatchResult:
+		for _, r := range out {
+			var result interface{}
+			if r.Result != nil {
+				result = r.Result
+			}
+			results, errs = append(results, result), append(errs, r.Err)
+		}
+	case []IPBatchResult:
+		for _, r := range out {
+			var result interface{}
+			if r.Result != nil {
+				result = r.Result
+			}
+			results, errs = append(results, result), append(errs, r.Err)
+		}
+	default:
+		return nil, nil, false
+	}
+	return results, errs, true
+}
+
+func (c *Client) batchUnsupported(path string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.noBatch[path]
+}
+
+func (c *Client) markBatchUnsupported(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.noBatch == nil {
+		c.noBatch = make(map[string]bool)
+	}
+	c.noBatch[path] = true
+}
+
+// forEach calls fn for 0..n-1 on up to the batch concurrency goroutines,
+// stopping early when ctx is done. With adaptive concurrency, it runs up to
+// the adaptive maximum and fn acquires slots from the limiter.
+func (c *Client) forEach(ctx context.Context, n int, fn func(i int)) {
+	workers := c.batchConcurrency
+	if c.adaptive != nil {
+		workers = c.adaptive.max()
+	}
+	if workers <= 0 {
+		workers = defaultBatchConcurrency
+	}
+	if workers > n {
+		workers = n
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(next)
+	wg.Wait()
+}
+
+// CheckBatch checks many emails, sending them to the batch endpoint in
+// chunks. Results are in input order; per-input failures, including
+// validation errors, are reported in Err. The returned error is non-nil if a
+// whole chunk failed or ctx ended early.
+// Unless ctx is tagged with WithPriority, it runs at PriorityBackground.
+func (s *EmailService) CheckBatch(ctx context.Context, emails []string) ([]EmailBatchResult, error) {
+	results, errs, err := s.client.checkBatch(ctx, batchSpec{
+		path:     "/email/check",
+		field:    "email",
+		validate: ValidateEmail,
+		override: func(in string) (interface{}, bool) {
+			return s.client.overrides.email(in)
+		},
+		decode: decodeEmailCheckResult,
+		single: func(ctx context.Context, in string) (interface{}, error) {
+			return s.Check(ctx, in)
+		},
+		pack: func(inputs []string, results []interface{}, errs []error) interface{} {
+			out := make([]EmailBatchResult, len(inputs))
+			for n, in := range inputs {
+				out[n] = EmailBatchResult{Input: in, Err: errs[n]}
+				out[n].Result, _ = results[n].(*EmailCheckResult)
+			}
+			return out
+		},
+	}, emails)
+	out := make([]EmailBatchResult, len(emails))
+	for i, in := range emails {
+		out[i] = EmailBatchResult{Input: in, Err: errs[i]}
+		if r, ok := results[i].(*EmailCheckResult); ok && r != nil && errs[i] == nil {
+			out[i].Result = r
+		} else if errs[i] == nil {
+			out[i].Err = ctxErrOr(ctx, baseError("Not checked", 0, nil))
+		}
+	}
+	return out, err
+}
+
+// CheckBatch checks many IPs, sending them to the batch endpoint in chunks.
+// Results are in input order; per-input failures, including validation
+// errors, are reported in Err. The returned error is non-nil if a whole
+// chunk failed or ctx ended early.
+// Unless ctx is tagged with WithPriority, it runs at PriorityBackground.
+func (s *IPService) CheckBatch(ctx context.Context, ips []string) ([]IPBatchResult, error) {
+	results, errs, err := s.client.checkBatch(ctx, batchSpec{
+		path:     "/ip/check",
+		field:    "ip",
+		validate: ValidateIP,
+		override: func(in string) (interface{}, bool) {
+			return s.local(in)
+		},
+		decode: decodeIPCheckResult,
+		single: func(ctx context.Context, in string) (interface{}, error) {
+			return s.Check(ctx, in)
+		},
+		pack: func(inputs []string, results []interface{}, errs []error) interface{} {
+			out := make([]IPBatchResult, len(inputs))
+			for n, in := range inputs {
+				out[n] = IPBatchResult{Input: in, Err: errs[n]}
+				out[n].Result, _ = results[n].(*IPCheckResult)
+			}
+			return out
+		},
+	}, ips)
+	out := make([]IPBatchResult, len(ips))
+	for i, in := range ips {
+		out[i] = IPBatchResult{Input: in, Err: errs[i]}
+		if r, ok := results[i].(*IPCheckResult); ok && r != nil && errs[i] == nil {
+			out[i].Result = r
+			s.client.prefixes.learn(r)
+		} else if errs[i] == nil {
+			out[i].Err = ctxErrOr(ctx, baseError("Not checked", 0, nil))
+		}
+	}
+	return out, err
+}
+
+func ctxErrOr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
@@ -0,0 +1,223 @@
+package sec4dev
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEmailCheckBatch_ChunksAndMapsErrors(t *testing.T) {
+	var mu sync.Mutex
+	var chunks [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/email/check/batch" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		var body struct{ Emails []string }
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		chunks = append(chunks, body.Emails)
+		mu.Unlock()
+		results := make([]interface{}, len(body.Emails))
+		for i, e := range body.Emails {
+			if strings.HasPrefix(e, "blocked") {
+				results[i] = map[string]interface{}{"email": e, "error": map[string]interface{}{"status": 422, "detail": "Unsupported domain"}}
+				continue
+			}
+			domain := e[strings.Index(e, "@")+1:]
+			results[i] = map[string]interface{}{"email": e, "domain": domain, "is_disposable": domain == "tempmail.com"}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL+"/api/v1"), WithHTTPClient(server.Client()),
+		WithBatchSize(2))
+	inputs := []string{"a@gmail.com", "bad", "b@tempmail.com", "blocked@x.com", "c@gmail.com"}
+	out, err := client.Email().CheckBatch(context.Background(), inputs)
+	if err != nil {
+		t.Fatalf("CheckBatch: %v", err)
+	}
+	if len(chunks) != 2 {
+		t.Errorf("chunks = %v, want 2 requests", chunks)
+	}
+	if len(out) != len(inputs) {
+		t.Fatalf("len(out) = %d", len(out))
+	}
+	if out[0].Result == nil || out[0].Result.IsDisposable || out[2].Result == nil || !out[2].Result.IsDisposable {
+		t.Errorf("results = %+v", out)
+	}
+	if _, ok := out[1].Err.(*ValidationError); !ok {
+		t.Errorf("out[1].Err = %T, want client-side ValidationError", out[1].Err)
+	}
+	if ve, ok := out[3].Err.(*ValidationError); !ok || ve.Message != "Unsupported domain" {
+		t.Errorf("out[3].Err = %#v", out[3].Err)
+	}
+	if out[4].Input != "c@gmail.com" || out[4].Result == nil || out[4].Result.Email != "c@gmail.com" {
+		t.Errorf("out[4] = %+v", out[4])
+	}
+}
+
+func TestIPCheckBatch_FallsBackToSingleChecks(t *testing.T) {
+	var mu sync.Mutex
+	batchCalls, singleCalls := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/api/v1/ip/check/batch" {
+			batchCalls++
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"detail": "Not Found"})
+			return
+		}
+		singleCalls++
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ip": body["ip"], "classification": "residential", "confidence": 0.9,
+			"signals": map[string]bool{"is_residential": true},
+		})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL+"/api/v1"), WithHTTPClient(server.Client()),
+		WithBatchConcurrency(3))
+	ctx := context.Background()
+	inputs := []string{"203.0.113.1", "203.0.113.2", "203.0.113.3", "203.0.113.4"}
+	for round := 0; round < 2; round++ {
+		out, err := client.IP().CheckBatch(ctx, inputs)
+		if err != nil {
+			t.Fatalf("CheckBatch: %v", err)
+		}
+		for i, r := range out {
+			if r.Err != nil || r.Result.IP != inputs[i] {
+				t.Errorf("out[%d] = %+v", i, r)
+			}
+		}
+	}
+	if batchCalls != 1 {
+		t.Errorf("batchCalls = %d, want the 404 remembered", batchCalls)
+	}
+	if singleCalls != 8 {
+		t.Errorf("singleCalls = %d, want 8", singleCalls)
+	}
+}
+
+func TestCheckBatch_FallbackKeepsConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/batch") {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"detail": "Not Found"})
+			return
+		}
+		mu.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(map[string]interface{}{"ip": body["ip"], "classification": "residential"})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL+"/api/v1"), WithHTTPClient(server.Client()),
+		WithBatchSize(2), WithBatchConcurrency(2))
+	inputs := []string{"203.0.113.1", "203.0.113.2", "203.0.113.3", "203.0.113.4",
+		"203.0.113.5", "203.0.113.6", "203.0.113.7", "203.0.113.8"}
+	out, err := client.IP().CheckBatch(context.Background(), inputs)
+	if err != nil {
+		t.Fatalf("CheckBatch: %v", err)
+	}
+	for i, r := range out {
+		if r.Err != nil {
+			t.Errorf("out[%d] = %+v", i, r)
+		}
+	}
+	if peak > 2 {
+		t.Errorf("peak single checks = %d, want at most the batch concurrency 2", peak)
+	}
+}
+
+func TestCheckBatch_UsesCache(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var body struct{ Emails []string }
+		json.NewDecoder(r.Body).Decode(&body)
+		results := make([]interface{}, len(body.Emails))
+		for i, e := range body.Emails {
+			results[i] = map[string]interface{}{"email": e, "domain": "gmail.com"}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL+"/api/v1"), WithHTTPClient(server.Client()),
+		WithCache(NewMemoryCache(0), time.Minute))
+	ctx := context.Background()
+	client.Email().CheckBatch(ctx, []string{"a@gmail.com", "b@gmail.com"})
+	out, _ := client.Email().CheckBatch(ctx, []string{"b@gmail.com", "a@gmail.com"})
+	if calls != 1 {
+		t.Errorf("calls = %d, want cached second batch", calls)
+	}
+	if out[0].Result == nil || out[0].Result.Email != "b@gmail.com" {
+		t.Errorf("out = %+v", out)
+	}
+	if _, err := client.Email().Check(ctx, "a@gmail.com"); err != nil || calls != 1 {
+		t.Errorf("Check after batch: err = %v, calls = %d", err, calls)
+	}
+}
+
+func TestCheckBatch_InterceptorSeesBatchResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ IPs []string }
+		json.NewDecoder(r.Body).Decode(&body)
+		results := make([]interface{}, len(body.IPs))
+		for i, ip := range body.IPs {
+			results[i] = map[string]interface{}{"ip": ip, "classification": "residential", "confidence": 0.9}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	}))
+	defer server.Close()
+
+	var paths []string
+	var seen []IPBatchResult
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithInterceptor(
+		func(ctx context.Context, req *Request, next Next) (*Response, error) {
+			resp, err := next(ctx, req)
+			paths = append(paths, req.Path)
+			if err == nil {
+				seen, _ = resp.Result.([]IPBatchResult)
+				// Interceptors may rewrite individual entries.
+				seen[1].Result.Classification = "reviewed"
+			}
+			return resp, err
+		}))
+	out, err := client.IP().CheckBatch(context.Background(), []string{"203.0.113.1", "203.0.113.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || paths[0] != "/ip/check/batch" {
+		t.Errorf("paths = %v", paths)
+	}
+	if len(seen) != 2 || seen[0].Input != "203.0.113.1" || seen[0].Result == nil || seen[0].Result.IP != "203.0.113.1" {
+		t.Errorf("interceptor saw %+v, want []IPBatchResult", seen)
+	}
+	if out[1].Result == nil || out[1].Result.Classification != "reviewed" {
+		t.Errorf("out[1] = %+v, want the interceptor's result", out[1])
+	}
+}
@@ -0,0 +1,162 @@
+package sec4dev
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// OverloadedError is returned when the bulkhead sheds a call: either its
+// wait queue was full or the call waited longer than the queue timeout.
+type OverloadedError struct {
+	*Sec4DevError
+	// QueueTimeout is set when the call timed out in the queue.
+	QueueTimeout bool
+}
+
+// Is makes errors.Is(err, ErrOverloaded) match any OverloadedError.
+func (e *OverloadedError) Is(target error) bool {
+	_, ok := target.(*OverloadedError)
+	return ok
+}
+
+// ErrOverloaded matches every OverloadedError with errors.Is.
+var ErrOverloaded error = &OverloadedError{Sec4DevError: baseError("Client overloaded", 0, nil)}
+
+// WithBulkhead caps the client at maxInFlight concurrent HTTP attempts.
+// Up to maxQueue more wait for at most queueTimeout (0 waits until the
+// context ends); beyond that, calls fail fast with an OverloadedError.
+// Waiters are served by priority, then in arrival order, and an interactive
+// call arriving at a full queue takes the place of the newest background
+// waiter. Overloaded calls are not retried.
+func WithBulkhead(maxInFlight, maxQueue int, queueTimeout time.Duration) ClientOption {
+	return func(c *Client) {
+		if maxInFlight <= 0 {
+			c.bulkhead = nil
+			return
+		}
+		c.bulkhead = &bulkhead{max: maxInFlight, maxQueue: maxQueue, timeout: queueTimeout}
+	}
+}
+
+type bulkhead struct {
+	mu       sync.Mutex
+	max      int
+	maxQueue int
+	timeout  time.Duration
+	inFlight int
+	queue    [numPriorities][]*bulkheadWaiter
+}
+
+type bulkheadWaiter struct {
+	ready chan struct{}
+	shed  bool // set before ready is closed when the waiter lost its place
+}
+
+func errQueueFull() error {
+	return &OverloadedError{Sec4DevError: baseError("Client overloaded: wait queue full", 0, nil)}
+}
+
+// queued returns the number of waiters. The caller holds b.mu.
+func (b *bulkhead) queued() int {
+	n := 0
+	for _, q := range b.queue {
+		n += len(q)
+	}
+	return n
+}
+
+// acquire takes a slot, waiting in the queue if needed. The returned
+// function releases it.
+func (b *bulkhead) acquire(ctx context.Context) (func(), error) {
+	if b == nil {
+		return func() {}, nil
+	}
+	p := PriorityFrom(ctx)
+	b.mu.Lock()
+	if b.inFlight < b.max && b.queued() == 0 {
+		b.inFlight++
+		b.mu.Unlock()
+		return b.release, nil
+	}
+	if b.queued() >= b.maxQueue {
+		bg := b.queue[PriorityBackground]
+		if p == PriorityBackground || len(bg) == 0 {
+			b.mu.Unlock()
+			return nil, errQueueFull()
+		}
+		last := bg[len(bg)-1]
+		b.queue[PriorityBackground] = bg[:len(bg)-1]
+		last.shed = true
+		close(last.ready)
+	}
+	w := &bulkheadWaiter{ready: make(chan struct{})}
+	b.queue[p] = append(b.queue[p], w)
+	b.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if b.timeout > 0 {
+		t := time.NewTimer(b.timeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case <-w.ready:
+		if w.shed {
+			return nil, errQueueFull()
+		}
+		return b.release, nil
+	case <-ctx.Done():
+		b.abandon(p, w)
+		return nil, ctx.Err()
+	case <-timeout:
+		b.abandon(p, w)
+		return nil, &OverloadedError{Sec4DevError: baseError("Client overloaded: timed out waiting for a slot", 0, nil), QueueTimeout: true}
+	}
+}
+
+// tryAcquire takes a slot only if one is free without queueing.
+func (b *bulkhead) tryAcquire() (func(), bool) {
+	if b == nil {
+		return func() {}, true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.inFlight >= b.max || b.queued() > 0 {
+		return nil, false
+	}
+	b.inFlight++
+	return b.release, true
+}
+
+// release hands the slot to the next waiter or frees it.
+func (b *bulkhead) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for p, q := range b.queue {
+		if len(q) > 0 {
+			close(q[0].ready)
+			b.queue[p] = q[1:]
+			return
+		}
+	}
+	b.inFlight--
+}
+
+// abandon removes a waiter that gave up. If it was granted a slot in the
+// meantime, the slot is passed on; if it was shed, there is nothing to do.
+func (b *bulkhead) abandon(p Priority, w *bulkheadWaiter) {
+	b.mu.Lock()
+	for i, x := range b.queue[p] {
+		if x == w {
+			b.queue[p] = append(b.queue[p][:i], b.queue[p][i+1:]...)
+			b.mu.Unlock()
+			return
+		}
+	}
+	shed := w.shed
+	b.mu.Unlock()
+	if !shed {
+		b.release()
+	}
+}
@@ -0,0 +1,123 @@
+package sec4dev
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingServer holds every request until release is closed and tracks the
+// peak number of concurrent requests.
+func blockingServer(release chan struct{}, inFlight, peak *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(inFlight, 1)
+		defer atomic.AddInt32(inFlight, -1)
+		for {
+			p := atomic.LoadInt32(peak)
+			if n <= p || atomic.CompareAndSwapInt32(peak, p, n) {
+				break
+			}
+		}
+		<-release
+		emailHandler(w, r)
+	}))
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not reached")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBulkhead_QueuesAndSheds(t *testing.T) {
+	release := make(chan struct{})
+	var inFlight, peak int32
+	server := blockingServer(release, &inFlight, &peak)
+	defer server.Close()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(0), WithBulkhead(2, 1, 0))
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = client.Email().Check(ctx, "user@gmail.com")
+		}(i)
+	}
+	waitFor(t, func() bool {
+		client.bulkhead.mu.Lock()
+		defer client.bulkhead.mu.Unlock()
+		return client.bulkhead.inFlight == 2 && client.bulkhead.queued() == 1
+	})
+
+	_, err := client.Email().Check(ctx, "user@gmail.com")
+	var oe *OverloadedError
+	if !errors.Is(err, ErrOverloaded) || !errors.As(err, &oe) || oe.QueueTimeout {
+		t.Fatalf("err = %v, want a full-queue OverloadedError", err)
+	}
+
+	// Both admitted calls must reach the server before they are released.
+	waitFor(t, func() bool { return atomic.LoadInt32(&inFlight) == 2 })
+	close(release)
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("call %d: %v", i, err)
+		}
+	}
+	if p := atomic.LoadInt32(&peak); p != 2 {
+		t.Errorf("peak concurrency = %d, want 2", p)
+	}
+}
+
+func TestBulkhead_QueueTimeoutAndCancel(t *testing.T) {
+	release := make(chan struct{})
+	var inFlight, peak int32
+	server := blockingServer(release, &inFlight, &peak)
+	defer server.Close()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(0), WithBulkhead(1, 5, 50*time.Millisecond))
+	ctx := context.Background()
+
+	done := make(chan error)
+	go func() {
+		_, err := client.Email().Check(ctx, "user@gmail.com")
+		done <- err
+	}()
+	waitFor(t, func() bool { return atomic.LoadInt32(&inFlight) == 1 })
+
+	start := time.Now()
+	_, err := client.Email().Check(ctx, "user@gmail.com")
+	var oe *OverloadedError
+	if !errors.As(err, &oe) || !oe.QueueTimeout || time.Since(start) < 50*time.Millisecond {
+		t.Fatalf("err = %v after %v, want a queue timeout", err, time.Since(start))
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := client.Email().Check(cctx, "user@gmail.com"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Email().Check(ctx, "user@gmail.com"); err != nil {
+		t.Errorf("slot leaked after timeouts: %v", err)
+	}
+}
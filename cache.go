@@ -0,0 +1,140 @@
+package sec4dev
+
+import (
+	"container/list"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// CacheEntry is a cached API response body.
+type CacheEntry struct {
+	Value     []byte
+	StoredAt  time.Time
+	ExpiresAt time.Time
+}
+
+// Cache stores successful check responses keyed by endpoint and input.
+// Implementations must be safe for concurrent use and must not return
+// entries past their ExpiresAt.
+type Cache interface {
+	Get(key string) (CacheEntry, bool)
+	Set(key string, entry CacheEntry)
+}
+
+// WithCache caches successful check results in cache for ttl.
+func WithCache(cache Cache, ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.cache = cache
+		c.cacheTTL = ttl
+	}
+}
+
+// MemoryCache is an in-process LRU Cache.
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+type memoryItem struct {
+	key   string
+	entry CacheEntry
+}
+
+// NewMemoryCache creates an LRU cache holding up to maxEntries results.
+// maxEntries <= 0 means unbounded.
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// Get returns the entry for key if present and not expired.
+func (m *MemoryCache) Get(key string) (CacheEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.items[key]
+	if !ok {
+		return CacheEntry{}, false
+	}
+	item := el.Value.(*memoryItem)
+	if !item.entry.ExpiresAt.IsZero() && time.Now().After(item.entry.ExpiresAt) {
+		m.ll.Remove(el)
+		delete(m.items, key)
+		return CacheEntry{}, false
+	}
+	m.ll.MoveToFront(el)
+	return item.entry, true
+}
+
+// Set stores entry under key, evicting the least recently used entry when full.
+func (m *MemoryCache) Set(key string, entry CacheEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.items[key]; ok {
+		el.Value.(*memoryItem).entry = entry
+		m.ll.MoveToFront(el)
+		return
+	}
+	m.items[key] = m.ll.PushFront(&memoryItem{key: key, entry: entry})
+	if m.maxEntries > 0 && m.ll.Len() > m.maxEntries {
+		oldest := m.ll.Back()
+		m.ll.Remove(oldest)
+		delete(m.items, oldest.Value.(*memoryItem).key)
+	}
+}
+
+// Len returns the number of cached entries, including expired ones not yet evicted.
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ll.Len()
+}
+
+func cacheKey(req *Request) string {
+	b, _ := json.Marshal(req.Body)
+	return req.Path + " " + string(b)
+}
+
+// cached answers req from the cache, if one is configured and holds a fresh
+// entry.
+func (c *Client) cached(req *Request, decode func([]byte) (interface{}, error)) (*Response, bool) {
+	if c.cache == nil {
+		return nil, false
+	}
+	resp, age, ok := c.lookup(req, decode)
+	if !ok || age > c.cacheTTL {
+		c.metrics.ObserveCacheMiss(req.Path)
+		return nil, false
+	}
+	c.metrics.ObserveCacheHit(req.Path)
+	return resp, true
+}
+
+// lookup returns the cached response for req, fresh or stale, and its age.
+func (c *Client) lookup(req *Request, decode func([]byte) (interface{}, error)) (*Response, time.Duration, bool) {
+	if c.cache == nil {
+		return nil, 0, false
+	}
+	entry, ok := c.cache.Get(cacheKey(req))
+	if !ok {
+		return nil, 0, false
+	}
+	result, err := decode(entry.Value)
+	if err != nil {
+		return nil, 0, false
+	}
+	return &Response{StatusCode: 200, Body: entry.Value, Result: result}, time.Since(entry.StoredAt), true
+}
+
+func (c *Client) store(req *Request, body []byte) {
+	if c.cache == nil || c.cacheTTL <= 0 {
+		return
+	}
+	now := time.Now()
+	c.cache.Set(cacheKey(req), CacheEntry{Value: body, StoredAt: now, ExpiresAt: now.Add(c.hardTTL())})
+}
@@ -0,0 +1,60 @@
+package sec4dev
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientCache_ServesRepeatChecks(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"email": "x@tempmail.com", "domain": "tempmail.com", "is_disposable": true,
+		})
+	}))
+	defer server.Close()
+
+	m := NewMetrics()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL+"/api/v1"), WithHTTPClient(server.Client()),
+		WithCache(NewMemoryCache(100), time.Minute), WithMetrics(m))
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		r, err := client.Email().Check(ctx, "x@tempmail.com")
+		if err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+		if !r.IsDisposable {
+			t.Errorf("result = %+v", r)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+	if m.cacheHits["/email/check"] != 2 || m.cacheMisses["/email/check"] != 1 {
+		t.Errorf("hits = %v, misses = %v", m.cacheHits, m.cacheMisses)
+	}
+}
+
+func TestMemoryCache_EvictsAndExpires(t *testing.T) {
+	c := NewMemoryCache(2)
+	far := time.Now().Add(time.Hour)
+	c.Set("a", CacheEntry{Value: []byte("1"), ExpiresAt: far})
+	c.Set("b", CacheEntry{Value: []byte("2"), ExpiresAt: far})
+	c.Get("a")
+	c.Set("c", CacheEntry{Value: []byte("3"), ExpiresAt: far})
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected least recently used entry to be evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("expected recently used entry to survive")
+	}
+	c.Set("d", CacheEntry{Value: []byte("4"), ExpiresAt: time.Now().Add(-time.Second)})
+	if _, ok := c.Get("d"); ok {
+		t.Error("expected expired entry to be dropped")
+	}
+}
@@ -1,8 +1,13 @@
 package sec4dev
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
+	"time"
 )
 
 const defaultBaseURL = "https://api.sec4.dev/api/v1"
@@ -15,20 +20,110 @@ type Client struct {
 	Retries      int
 	RetryDelayMs int
 	onRateLimit  func(RateLimitInfo)
-	rateLimit    RateLimitInfo
+	mu           sync.Mutex
+	rateLimits   map[string]RateLimitInfo // by KeyPool key name
+	interceptors []Interceptor
+	metrics      *Metrics
+	quota        *QuotaTracker
+	limiter      *limiter
+	hedger       *hedger
+	bulkhead     *bulkhead
+	adaptive     *AdaptiveLimiter
+	endpoints    *endpoints
+	cache        Cache
+	cacheTTL     time.Duration
+	refreshing   map[string]bool
+	coalescing   bool
+	flights      map[string]*flight
+	timeout      time.Duration
+	clock        Clock
+	jitter       *lockedRand
+	proxyURL     *url.URL
+	credentials  CredentialProvider
+	signupPolicy SignupPolicy
+	overrides    *Overrides
+	prefixes     *prefixCache
+
+	staleWhileRevalidate time.Duration
+	staleIfError         time.Duration
+	attemptTimeout       time.Duration
+	operationTimeout     time.Duration
+
+	compressThreshold int
+	batchSize         int
+	batchConcurrency  int
+	noBatch           map[string]bool
+
+	transportConfig TransportConfig
+	rootCAs         *x509.CertPool
+	clientCerts     []tls.Certificate
+	defaultHTTPOnce sync.Once
+	defaultHTTP     *http.Client
 }
 
 // ClientOption configures the client.
 type ClientOption func(*Client)
 
-// WithBaseURL sets the API base URL.
-func WithBaseURL(url string) ClientOption {
+// WithBaseURL sets the API base URL. Fallback URLs, such as a regional host
+// or the public API behind an internal gateway, are tried in order when a
+// request to the ones before them fails with a network error or 5xx. A URL
+// that fails repeatedly is skipped until it is re-probed (see
+// WithEndpointProbeInterval).
+func WithBaseURL(url string, fallbacks ...string) ClientOption {
 	return func(c *Client) {
 		c.BaseURL = strings.TrimSuffix(url, "/")
+		if len(fallbacks) == 0 {
+			if c.endpoints != nil {
+				c.endpoints.fallbacks = nil
+			}
+			return
+		}
+		if c.endpoints == nil {
+			c.endpoints = &endpoints{}
+		}
+		c.endpoints.fallbacks = make([]string, len(fallbacks))
+		for i, u := range fallbacks {
+			c.endpoints.fallbacks[i] = strings.TrimSuffix(u, "/")
+		}
+	}
+}
+
+// WithTimeout sets the overall per-request timeout of the default HTTP
+// client (default: 40s); 0 disables it. It has no effect when WithHTTPClient
+// is used.
+func WithTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithAttemptTimeout bounds each HTTP attempt, including reading the
+// response, so a hung connection fails over or is retried instead of using up
+// the caller's deadline. It applies with any HTTP client.
+func WithAttemptTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.attemptTimeout = d
+	}
+}
+
+// WithOperationTimeout bounds a call's attempts and the waits between them,
+// across every API key tried.
+// A retry whose wait would not finish before this or the caller's deadline is
+// not made, and the last error is returned instead.
+func WithOperationTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.operationTimeout = d
+	}
+}
+
+// WithProxy routes requests through proxyURL. It has no effect when
+// WithHTTPClient is used.
+func WithProxy(proxyURL *url.URL) ClientOption {
+	return func(c *Client) {
+		c.proxyURL = proxyURL
 	}
 }
 
-// WithTimeout is not used directly; use a custom HTTPClient with timeout.
 // WithRetries sets the number of retries.
 func WithRetries(n int) ClientOption {
 	return func(c *Client) {
@@ -63,21 +158,40 @@ func NewClient(apiKey string, opts ...ClientOption) (*Client, error) {
 	if key == "" || !strings.HasPrefix(key, "sec4_") {
 		return nil, &ValidationError{baseError("API key must start with sec4_", 422, nil)}
 	}
+	return newClient(key, opts), nil
+}
+
+func newClient(key string, opts []ClientOption) *Client {
 	c := &Client{
 		APIKey:       key,
 		BaseURL:      defaultBaseURL,
 		Retries:      3,
 		RetryDelayMs: 1000,
+		timeout:      connectTimeout + readTimeout,
+		clock:        systemClock{},
 	}
 	for _, o := range opts {
 		o(c)
 	}
-	return c, nil
+	return c
 }
 
-// RateLimit returns the last rate limit info.
+// RateLimit returns the last rate limit info. With a KeyPool it covers the
+// whole pool: Limit and Remaining are summed over the keys' last values and
+// ResetSeconds is the soonest reset. KeyPool.Status has each key's own.
 func (c *Client) RateLimit() RateLimitInfo {
-	return c.rateLimit
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return sumRateLimits(c.rateLimits)
+}
+
+func (c *Client) observeRateLimit(key string, rl RateLimitInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rateLimits == nil {
+		c.rateLimits = make(map[string]RateLimitInfo)
+	}
+	c.rateLimits[key] = rl
 }
 
 // Email returns the email service.
@@ -0,0 +1,113 @@
+package sec4dev
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Clock supplies the current time, the waits between retries and the
+// operation timeout. The default is the system clock; tests can inject a fake
+// one with WithClock to cover backoff without real sleeps.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single-shot timer from a Clock, like *time.Timer.
+type Timer interface {
+	// C delivers the time once the timer fires.
+	C() <-chan time.Time
+	// Stop prevents the timer from firing. It reports whether it was still
+	// pending.
+	Stop() bool
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                 { return time.Now() }
+func (systemClock) NewTimer(d time.Duration) Timer { return systemTimer{time.NewTimer(d)} }
+
+type systemTimer struct{ t *time.Timer }
+
+func (t systemTimer) C() <-chan time.Time { return t.t.C }
+func (t systemTimer) Stop() bool          { return t.t.Stop() }
+
+// WithClock sets the clock used to wait between retries, to time the
+// operation timeout and to check whether a wait would outlast the deadline.
+// A caller's own context deadline is compared with the clock's Now, so a fake
+// clock should start near the real time. Nil restores the system clock.
+func WithClock(clk Clock) ClientOption {
+	return func(c *Client) {
+		if clk == nil {
+			clk = systemClock{}
+		}
+		c.clock = clk
+	}
+}
+
+// WithRandSource sets the source of the retry backoff jitter, for
+// reproducible delays.
+func WithRandSource(src rand.Source) ClientOption {
+	return func(c *Client) {
+		c.jitter = &lockedRand{rng: rand.New(src)}
+	}
+}
+
+// lockedRand makes a *rand.Rand safe for concurrent calls.
+type lockedRand struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func (r *lockedRand) Intn(n int) int {
+	if r == nil {
+		return rand.Intn(n)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.Intn(n)
+}
+
+// withTimeout is context.WithTimeout timed by the client's clock. The
+// returned context's Deadline is in the clock's time.
+func (c *Client) withTimeout(parent context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := c.clock.(systemClock); ok {
+		return context.WithTimeout(parent, d)
+	}
+	deadline := c.clock.Now().Add(d)
+	ctx, cancel := context.WithCancelCause(parent)
+	timer := c.clock.NewTimer(d)
+	go func() {
+		select {
+		case <-timer.C():
+			cancel(context.DeadlineExceeded)
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}()
+	return &clockContext{Context: ctx, deadline: deadline}, func() { cancel(context.Canceled) }
+}
+
+// clockContext reports a deadline set by a Clock, and DeadlineExceeded once
+// it passes.
+type clockContext struct {
+	context.Context
+	deadline time.Time
+}
+
+func (c *clockContext) Deadline() (time.Time, bool) {
+	if d, ok := c.Context.Deadline(); ok && d.Before(c.deadline) {
+		return d, true
+	}
+	return c.deadline, true
+}
+
+func (c *clockContext) Err() error {
+	err := c.Context.Err()
+	if err != nil && context.Cause(c.Context) == context.DeadlineExceeded {
+		return context.DeadlineExceeded
+	}
+	return err
+}
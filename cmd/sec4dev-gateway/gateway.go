@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sec4dev/sec4dev-go"
+)
+
+const maxBodyBytes = 64 << 10
+
+// gateway serves the check API to downstream clients through one upstream
+// client.
+type gateway struct {
+	client  *sec4dev.Client
+	pool    *sec4dev.KeyPool // nil without -api-keys
+	metrics *sec4dev.Metrics
+	tokens  []token
+
+	mu       sync.Mutex
+	requests map[[3]string]uint64 // client, endpoint, code
+}
+
+func newGateway(client *sec4dev.Client, pool *sec4dev.KeyPool, metrics *sec4dev.Metrics, tokens []token) *gateway {
+	return &gateway{
+		client:   client,
+		pool:     pool,
+		metrics:  metrics,
+		tokens:   tokens,
+		requests: make(map[[3]string]uint64),
+	}
+}
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1")
+	switch path {
+	case "/healthz":
+		g.health(w, r)
+	case "/metrics":
+		g.serveMetrics(w, r)
+	case "/email/check", "/ip/check":
+		g.check(w, r, path)
+	default:
+		writeError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+// check answers one email or IP check.
+func (g *gateway) check(w http.ResponseWriter, r *http.Request, path string) {
+	name, ok := authenticate(g.tokens, requestToken(r))
+	if !ok {
+		g.count("", path, http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, "Invalid or missing API key")
+		return
+	}
+	if r.Method != http.MethodPost {
+		g.count(name, path, http.StatusMethodNotAllowed)
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+	var body struct {
+		Email string `json:"email"`
+		IP    string `json:"ip"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&body); err != nil {
+		g.count(name, path, http.StatusUnprocessableEntity)
+		writeError(w, http.StatusUnprocessableEntity, "Invalid JSON body")
+		return
+	}
+
+	ctx := r.Context()
+	if strings.EqualFold(r.Header.Get("X-Sec4dev-Priority"), "background") {
+		ctx = sec4dev.WithPriority(ctx, sec4dev.PriorityBackground)
+	}
+	var result interface{}
+	var err error
+	if path == "/email/check" {
+		result, err = g.client.Email().Check(ctx, body.Email)
+	} else {
+		result, err = g.client.IP().Check(ctx, body.IP)
+	}
+	if rl := g.client.RateLimit(); rl.Limit > 0 {
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rl.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(rl.Remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.Itoa(rl.ResetSeconds))
+	}
+	if err != nil {
+		status, msg := errorStatus(ctx, w, err)
+		g.count(name, path, status)
+		writeError(w, status, msg)
+		return
+	}
+	g.count(name, path, http.StatusOK)
+	writeJSON(w, http.StatusOK, result)
+}
+
+// requestToken returns the token from X-API-Key, as the SDKs send it, or from
+// an Authorization: Bearer header.
+func requestToken(r *http.Request) string {
+	if v := r.Header.Get("X-API-Key"); v != "" {
+		return v
+	}
+	if v := r.Header.Get("Authorization"); len(v) > 7 && strings.EqualFold(v[:7], "Bearer ") {
+		return strings.TrimSpace(v[7:])
+	}
+	return ""
+}
+
+// errorStatus maps an SDK error to the status and message returned
+// downstream. Upstream authentication failures are the gateway's problem,
+// not the caller's, so they become 502.
+func errorStatus(ctx context.Context, w http.ResponseWriter, err error) (int, string) {
+	switch e := err.(type) {
+	case *sec4dev.ValidationError:
+		return http.StatusUnprocessableEntity, e.Message
+	case *sec4dev.RateLimitError:
+		if e.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(e.RetryAfter))
+		}
+		return http.StatusTooManyRequests, e.Message
+	case *sec4dev.PaymentRequiredError:
+		return http.StatusPaymentRequired, e.Message
+	case *sec4dev.NotFoundError:
+		return http.StatusNotFound, e.Message
+	case *sec4dev.AuthenticationError, *sec4dev.ForbiddenError:
+		return http.StatusBadGateway, "Upstream rejected the gateway's API key"
+	case *sec4dev.OverloadedError:
+		return http.StatusServiceUnavailable, e.Message
+	case *sec4dev.ServerError:
+		return http.StatusBadGateway, e.Message
+	}
+	if errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+		return http.StatusGatewayTimeout, "Upstream timed out"
+	}
+	return http.StatusBadGateway, "Upstream unavailable"
+}
+
+type healthReport struct {
+	Status    string           `json:"status"`
+	Endpoints []endpointHealth `json:"endpoints"`
+	Keys      []keyHealth      `json:"keys,omitempty"`
+}
+
+type endpointHealth struct {
+	URL       string `json:"url"`
+	Healthy   bool   `json:"healthy"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+type keyHealth struct {
+	Name      string `json:"name"`
+	Available bool   `json:"available"`
+	Remaining int    `json:"remaining"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// health reports "ok" when an upstream endpoint and an API key are usable,
+// else "degraded" with status 503.
+func (g *gateway) health(w http.ResponseWriter, r *http.Request) {
+	report := healthReport{Status: "ok"}
+	anyEndpoint := false
+	for _, e := range g.client.Endpoints() {
+		report.Endpoints = append(report.Endpoints, endpointHealth{URL: e.URL, Healthy: e.Healthy, LastError: e.LastError})
+		anyEndpoint = anyEndpoint || e.Healthy
+	}
+	anyKey := g.pool == nil
+	if g.pool != nil {
+		for _, k := range g.pool.Status() {
+			kh := keyHealth{Name: k.Name, Available: k.Available, Remaining: k.RateLimit.Remaining}
+			if k.LastError != nil {
+				kh.LastError = k.LastError.Error()
+			}
+			report.Keys = append(report.Keys, kh)
+			anyKey = anyKey || k.Available
+		}
+	}
+	status := http.StatusOK
+	if !anyEndpoint || !anyKey {
+		report.Status = "degraded"
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+// serveMetrics writes the client's metrics followed by the gateway's own.
+func (g *gateway) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	g.metrics.WriteTo(bw)
+
+	g.mu.Lock()
+	keys := make([][3]string, 0, len(g.requests))
+	for k := range g.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		for n := range keys[i] {
+			if keys[i][n] != keys[j][n] {
+				return keys[i][n] < keys[j][n]
+			}
+		}
+		return false
+	})
+	fmt.Fprintln(bw, "# HELP sec4dev_gateway_requests_total Check requests served to downstream clients.")
+	fmt.Fprintln(bw, "# TYPE sec4dev_gateway_requests_total counter")
+	for _, k := range keys {
+		fmt.Fprintf(bw, "sec4dev_gateway_requests_total{client=%s,endpoint=%s,code=\"%s\"} %d\n", quoteLabel(k[0]), quoteLabel(k[1]), k[2], g.requests[k])
+	}
+	g.mu.Unlock()
+	bw.Flush()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quoteLabel quotes v as a Prometheus label value.
+func quoteLabel(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
+
+func (g *gateway) count(client, endpoint string, status int) {
+	g.mu.Lock()
+	g.requests[[3]string{client, endpoint, strconv.Itoa(status)}]++
+	g.mu.Unlock()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error in the API's {"detail": ...} shape.
+func writeError(w http.ResponseWriter, status int, detail string) {
+	writeJSON(w, status, map[string]string{"detail": detail})
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sec4dev/sec4dev-go"
+)
+
+func newTestGateway(t *testing.T, upstream http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	api := httptest.NewServer(upstream)
+	t.Cleanup(api.Close)
+	metrics := sec4dev.NewMetrics()
+	client, err := sec4dev.NewClient("sec4_test", sec4dev.WithBaseURL(api.URL), sec4dev.WithRetries(0),
+		sec4dev.WithMetrics(metrics), sec4dev.WithCoalescing(),
+		sec4dev.WithCache(sec4dev.NewMemoryCache(100), time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := []token{{name: "billing", value: []byte("local-billing")}, {name: "signup", value: []byte("local-signup")}}
+	gw := httptest.NewServer(newGateway(client, nil, metrics, tokens))
+	t.Cleanup(gw.Close)
+	return gw
+}
+
+func post(t *testing.T, url, token, body string) (*http.Response, map[string]interface{}) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("X-API-Key", token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&out)
+	return resp, out
+}
+
+func TestGateway_ChecksShareCacheAndRequests(t *testing.T) {
+	var upstreamCalls int32
+	gw := newTestGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&upstreamCalls, 1)
+		if r.Header.Get("X-API-Key") != "sec4_test" {
+			t.Errorf("upstream key = %q", r.Header.Get("X-API-Key"))
+		}
+		time.Sleep(20 * time.Millisecond)
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("X-RateLimit-Limit", "1000")
+		w.Header().Set("X-RateLimit-Remaining", "999")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"email": body["email"], "domain": "tempmail.com", "is_disposable": true,
+		})
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tok := "local-billing"
+			if i%2 == 1 {
+				tok = "local-signup"
+			}
+			resp, out := post(t, gw.URL+"/api/v1/email/check", tok, `{"email":"user@tempmail.com"}`)
+			if resp.StatusCode != http.StatusOK || out["is_disposable"] != true || out["domain"] != "tempmail.com" {
+				t.Errorf("status %d, body %v", resp.StatusCode, out)
+			}
+		}(i)
+	}
+	wg.Wait()
+	resp, _ := post(t, gw.URL+"/email/check", "local-billing", `{"email":"user@tempmail.com"}`)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-RateLimit-Remaining") != "999" {
+		t.Errorf("status %d, headers %v", resp.StatusCode, resp.Header)
+	}
+	if n := atomic.LoadInt32(&upstreamCalls); n != 1 {
+		t.Errorf("upstream calls = %d, want 1", n)
+	}
+
+	mresp, err := http.Get(gw.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics, _ := io.ReadAll(mresp.Body)
+	mresp.Body.Close()
+	for _, want := range []string{
+		`sec4dev_gateway_requests_total{client="billing",endpoint="/email/check",code="200"} 4`,
+		`sec4dev_gateway_requests_total{client="signup",endpoint="/email/check",code="200"} 2`,
+		`sec4dev_requests_total{endpoint="/email/check",code="200"} 1`,
+	} {
+		if !bytes.Contains(metrics, []byte(want)) {
+			t.Errorf("metrics missing %s:\n%s", want, metrics)
+		}
+	}
+}
+
+func TestGateway_AuthAndErrors(t *testing.T) {
+	gw := newTestGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"detail": "Invalid API key"})
+	})
+
+	resp, out := post(t, gw.URL+"/ip/check", "", `{"ip":"203.0.113.1"}`)
+	if resp.StatusCode != http.StatusUnauthorized || out["detail"] == nil {
+		t.Errorf("no token: status %d, body %v", resp.StatusCode, out)
+	}
+	resp, _ = post(t, gw.URL+"/ip/check", "sec4_test", `{"ip":"203.0.113.1"}`)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("upstream key as token: status %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, gw.URL+"/ip/check", strings.NewReader(`{"ip":"not-an-ip"}`))
+	req.Header.Set("Authorization", "Bearer local-signup")
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Body.Close()
+	if r.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("invalid IP: status %d, want 422", r.StatusCode)
+	}
+
+	resp, out = post(t, gw.URL+"/ip/check", "local-signup", `{"ip":"203.0.113.1"}`)
+	if resp.StatusCode != http.StatusBadGateway || out["detail"] != "Upstream rejected the gateway's API key" {
+		t.Errorf("upstream 401: status %d, body %v", resp.StatusCode, out)
+	}
+}
+
+func TestGateway_Health(t *testing.T) {
+	gw := newTestGateway(t, func(w http.ResponseWriter, r *http.Request) {})
+	resp, err := http.Get(gw.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var report healthReport
+	json.NewDecoder(resp.Body).Decode(&report)
+	if resp.StatusCode != http.StatusOK || report.Status != "ok" || len(report.Endpoints) != 1 || !report.Endpoints[0].Healthy {
+		t.Errorf("status %d, report %+v", resp.StatusCode, report)
+	}
+}
+
+func TestReadTokens(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tokens")
+	os.WriteFile(path, []byte("# downstream services\nbilling tok-1\n\nsignup tok-2\n"), 0o600)
+	tokens, err := readTokens(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, ok := authenticate(tokens, "tok-2"); !ok || name != "signup" {
+		t.Errorf("authenticate(tok-2) = %q, %v", name, ok)
+	}
+	if _, ok := authenticate(tokens, "tok-3"); ok {
+		t.Error("unknown token accepted")
+	}
+
+	os.WriteFile(path, []byte("billing tok-1\nbilling tok-2\n"), 0o600)
+	if _, err := readTokens(path); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("err = %v, want duplicate name", err)
+	}
+}
+
+func TestQuoteLabel_Escapes(t *testing.T) {
+	if got := quoteLabel("clé\"b\\c\nd"); got != `"clé\"b\\c\nd"` {
+		t.Errorf("quoteLabel = %s", got)
+	}
+}
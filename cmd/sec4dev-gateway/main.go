@@ -0,0 +1,161 @@
+// Command sec4dev-gateway serves the Sec4Dev check API to local services
+// through one shared client, so they share its cache, quota and API keys.
+//
+// Usage:
+//
+//	sec4dev-gateway -tokens FILE [flags]
+//
+// It answers POST /email/check and POST /ip/check (also under /api/v1) with
+// the same request and response bodies as the Sec4Dev API. Callers
+// authenticate with a local token in X-API-Key or an Authorization: Bearer
+// header; the tokens file holds one "name token" pair per line. Identical
+// concurrent checks share one upstream request, and results are cached.
+// GET /healthz reports upstream endpoint and key health, and GET /metrics
+// serves Prometheus metrics.
+//
+// The upstream client is configured like NewClientFromEnv: SEC4DEV_API_KEY,
+// SEC4DEV_BASE_URL, SEC4DEV_CONFIG_FILE and the other SEC4DEV_* variables.
+// With -api-keys, calls are spread over a pool of keys instead, read from a
+// file of "name key [weight]" lines.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sec4dev/sec4dev-go"
+)
+
+const defaultCacheTTL = 5 * time.Minute
+
+// options holds the command-line flags.
+type options struct {
+	listen      string
+	tokensPath  string
+	apiKeysPath string
+	rate        float64
+	burst       int
+	maxInFlight int
+	cacheTTL    time.Duration
+	cacheSize   int
+	staleIfErr  time.Duration
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("sec4dev-gateway", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var o options
+	fs.StringVar(&o.listen, "listen", ":8080", "address to listen on")
+	fs.StringVar(&o.tokensPath, "tokens", os.Getenv("SEC4DEV_GATEWAY_TOKENS_FILE"), `file of "name token" lines for downstream clients (default $SEC4DEV_GATEWAY_TOKENS_FILE)`)
+	fs.StringVar(&o.apiKeysPath, "api-keys", "", `file of "name key [weight]" lines to pool upstream API keys`)
+	fs.Float64Var(&o.rate, "rate", 0, "maximum upstream requests per second (0 for unlimited)")
+	fs.IntVar(&o.burst, "burst", 1, "upstream request burst")
+	fs.IntVar(&o.maxInFlight, "max-in-flight", 0, "maximum concurrent upstream requests (0 for unlimited)")
+	fs.DurationVar(&o.cacheTTL, "cache-ttl", 0, "result cache TTL (default: the config's, else 5m)")
+	fs.IntVar(&o.cacheSize, "cache-size", 10000, "maximum cached results")
+	fs.DurationVar(&o.staleIfErr, "stale-if-error", 0, "serve cached results up to this long past their TTL when the API is down")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if o.tokensPath == "" {
+		fmt.Fprintln(stderr, "sec4dev-gateway: -tokens is required")
+		return 2
+	}
+
+	tokens, err := readTokens(o.tokensPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "sec4dev-gateway: %v\n", err)
+		return 2
+	}
+	metrics := sec4dev.NewMetrics()
+	client, pool, err := newClient(o, metrics)
+	if err != nil {
+		fmt.Fprintf(stderr, "sec4dev-gateway: %v\n", err)
+		return 2
+	}
+
+	srv := &http.Server{
+		Addr:              o.listen,
+		Handler:           newGateway(client, pool, metrics, tokens),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err = srv.Shutdown(shutdownCtx)
+		cancel()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(stderr, "sec4dev-gateway: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// newClient builds the upstream client from the SEC4DEV_* configuration and
+// the flags, which take precedence.
+func newClient(o options, metrics *sec4dev.Metrics) (*sec4dev.Client, *sec4dev.KeyPool, error) {
+	cfg, err := sec4dev.LoadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	opts := []sec4dev.ClientOption{sec4dev.WithMetrics(metrics), sec4dev.WithCoalescing()}
+	switch {
+	case o.cacheTTL > 0:
+		opts = append(opts, sec4dev.WithCache(sec4dev.NewMemoryCache(o.cacheSize), o.cacheTTL))
+	case cfg.CacheTTLSeconds == nil:
+		opts = append(opts, sec4dev.WithCache(sec4dev.NewMemoryCache(o.cacheSize), defaultCacheTTL))
+	}
+	if o.staleIfErr > 0 {
+		opts = append(opts, sec4dev.WithStaleIfError(o.staleIfErr))
+	}
+	if o.rate > 0 {
+		opts = append(opts, sec4dev.WithRequestRate(o.rate, o.burst))
+	}
+	if o.maxInFlight > 0 {
+		opts = append(opts, sec4dev.WithBulkhead(o.maxInFlight, 4*o.maxInFlight, 5*time.Second))
+	}
+
+	if o.apiKeysPath == "" {
+		if cfg.APIKey == "" && cfg.APIKeyFile == "" {
+			return nil, nil, errors.New("no API key: set $SEC4DEV_API_KEY, $SEC4DEV_API_KEY_FILE or use -api-keys")
+		}
+		client, err := sec4dev.NewClientFromConfig(cfg, opts...)
+		return client, nil, err
+	}
+	keys, err := readPoolKeys(o.apiKeysPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	pool, err := sec4dev.NewKeyPool(sec4dev.KeyPoolConfig{Keys: keys})
+	if err != nil {
+		return nil, nil, err
+	}
+	cfgOpts, err := cfg.Options()
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := sec4dev.NewClientWithCredentials(pool, append(cfgOpts, opts...)...)
+	return client, pool, err
+}
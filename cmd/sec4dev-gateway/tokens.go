@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sec4dev/sec4dev-go"
+)
+
+// token is a downstream client's credential.
+type token struct {
+	name  string
+	value []byte
+}
+
+// readTokens reads "name token" lines. Blank lines and lines starting with
+// # are skipped.
+func readTokens(path string) ([]token, error) {
+	var tokens []token
+	names := make(map[string]bool)
+	err := readFields(path, func(line int, fields []string) error {
+		if len(fields) != 2 {
+			return fmt.Errorf("%s:%d: want \"name token\"", path, line)
+		}
+		if names[fields[0]] {
+			return fmt.Errorf("%s:%d: duplicate name %q", path, line, fields[0])
+		}
+		names[fields[0]] = true
+		tokens = append(tokens, token{name: fields[0], value: []byte(fields[1])})
+		return nil
+	})
+	if err == nil && len(tokens) == 0 {
+		err = fmt.Errorf("%s: no tokens", path)
+	}
+	return tokens, err
+}
+
+// authenticate returns the name of the client holding value. Every token is
+// compared in constant time.
+func authenticate(tokens []token, value string) (string, bool) {
+	name, ok := "", false
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare(t.value, []byte(value)) == 1 {
+			name, ok = t.name, true
+		}
+	}
+	return name, ok
+}
+
+// readPoolKeys reads "name key [weight]" lines.
+func readPoolKeys(path string) ([]sec4dev.PoolKey, error) {
+	var keys []sec4dev.PoolKey
+	err := readFields(path, func(line int, fields []string) error {
+		if len(fields) < 2 || len(fields) > 3 {
+			return fmt.Errorf("%s:%d: want \"name key [weight]\"", path, line)
+		}
+		k := sec4dev.PoolKey{Name: fields[0], Key: fields[1]}
+		if len(fields) == 3 {
+			w, err := strconv.Atoi(fields[2])
+			if err != nil || w <= 0 {
+				return fmt.Errorf("%s:%d: weight must be a positive integer", path, line)
+			}
+			k.Weight = w
+		}
+		keys = append(keys, k)
+		return nil
+	})
+	return keys, err
+}
+
+func readFields(path string, fn func(line int, fields []string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := fn(n, strings.Fields(line)); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/sec4dev/sec4dev-go"
+)
+
+// kind describes one type of check: how to run it, when its result is
+// flagged, and how to lay it out as columns.
+type kind struct {
+	name    string
+	check   func(ctx context.Context, c *sec4dev.Client, input string) (interface{}, error)
+	flagged func(result interface{}) bool
+	columns []string
+	row     func(result interface{}) []string
+}
+
+var emailKind = kind{
+	name: "email",
+	check: func(ctx context.Context, c *sec4dev.Client, input string) (interface{}, error) {
+		return c.Email().Check(ctx, input)
+	},
+	flagged: func(result interface{}) bool {
+		return result.(*sec4dev.EmailCheckResult).IsDisposable
+	},
+	columns: []string{"domain", "disposable"},
+	row: func(result interface{}) []string {
+		r := result.(*sec4dev.EmailCheckResult)
+		return []string{r.Domain, strconv.FormatBool(r.IsDisposable)}
+	},
+}
+
+var ipKind = kind{
+	name: "ip",
+	check: func(ctx context.Context, c *sec4dev.Client, input string) (interface{}, error) {
+		return c.IP().Check(ctx, input)
+	},
+	flagged: func(result interface{}) bool {
+		r := result.(*sec4dev.IPCheckResult)
+		s := r.Signals
+		return r.Overridden == sec4dev.OverrideDeny || s.IsTor || s.IsVPN || s.IsProxy || s.IsHosting
+	},
+	columns: []string{"classification", "confidence", "tor", "vpn", "proxy", "hosting", "country", "provider"},
+	row: func(result interface{}) []string {
+		r := result.(*sec4dev.IPCheckResult)
+		return []string{
+			r.Classification,
+			strconv.FormatFloat(r.Confidence, 'f', 2, 64),
+			strconv.FormatBool(r.Signals.IsTor),
+			strconv.FormatBool(r.Signals.IsVPN),
+			strconv.FormatBool(r.Signals.IsProxy),
+			strconv.FormatBool(r.Signals.IsHosting),
+			r.Geo.Country,
+			r.Network.Provider,
+		}
+	},
+}
+
+// record is one checked input as written to the output.
+type record struct {
+	Input   string      `json:"input"`
+	Flagged bool        `json:"flagged"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+type summary struct {
+	flagged int
+	failed  int
+}
+
+// checkAll checks inputs with up to concurrency workers and writes records to
+// w in input order as they complete. With adaptive concurrency, workers also
+// take a slot from the client's adaptive limiter.
+func checkAll(ctx context.Context, client *sec4dev.Client, k kind, inputs []string, concurrency int, w recordWriter) summary {
+	type indexed struct {
+		i   int
+		rec record
+	}
+	jobs := make(chan int)
+	done := make(chan indexed)
+	var wg sync.WaitGroup
+	for n := 0; n < concurrency && n < len(inputs); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				rec := record{Input: inputs[i]}
+				release, err := client.Adaptive().Acquire(ctx)
+				if err != nil {
+					rec.Error = err.Error()
+					done <- indexed{i, rec}
+					continue
+				}
+				result, err := k.check(ctx, client, inputs[i])
+				release()
+				if err != nil {
+					rec.Error = err.Error()
+				} else {
+					rec.Result = result
+					rec.Flagged = k.flagged(result)
+				}
+				done <- indexed{i, rec}
+			}
+		}()
+	}
+	go func() {
+		for i := range inputs {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(done)
+	}()
+
+	var sum summary
+	pending := make(map[int]record)
+	next := 0
+	var writeErr error
+	for d := range done {
+		pending[d.i] = d.rec
+		for {
+			rec, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if rec.Error != "" {
+				sum.failed++
+			} else if rec.Flagged {
+				sum.flagged++
+			}
+			if writeErr == nil {
+				writeErr = w.Write(rec)
+			}
+		}
+	}
+	if writeErr != nil {
+		sum.failed++
+	}
+	return sum
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/sec4dev/sec4dev-go"
+)
+
+// loadConfig builds the client config from the config file, then the
+// SEC4DEV_* environment variables, then flags, each overriding the last.
+func loadConfig(o options) (*sec4dev.Config, error) {
+	path, explicit := o.configPath, o.configPath != ""
+	if !explicit {
+		path = firstNonEmpty(os.Getenv(sec4dev.EnvConfigFile), defaultConfigPath())
+	}
+	cfg := &sec4dev.Config{}
+	if path != "" {
+		fileCfg, err := sec4dev.ReadConfigFile(path)
+		switch {
+		case err == nil:
+			cfg = fileCfg
+		case explicit || !errors.Is(err, os.ErrNotExist):
+			return nil, err
+		}
+	}
+	if err := cfg.LoadEnv(); err != nil {
+		return nil, err
+	}
+	if o.apiKey != "" {
+		cfg.APIKey = o.apiKey
+	}
+	if o.baseURL != "" {
+		cfg.BaseURL = o.baseURL
+	}
+	if o.retries >= 0 {
+		cfg.Retries = &o.retries
+	}
+	if cfg.APIKey == "" && cfg.APIKeyFile == "" {
+		return nil, errors.New("no API key: use -api-key, $SEC4DEV_API_KEY, $SEC4DEV_API_KEY_FILE or the config file")
+	}
+	return cfg, nil
+}
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "sec4dev", "config.json")
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
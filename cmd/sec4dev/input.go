@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// readFiles reads inputs from each path in order; "-" or no paths reads stdin.
+func readFiles(paths []string, stdin io.Reader, format, column string) ([]string, error) {
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	var all []string
+	for _, p := range paths {
+		fmtp := format
+		if fmtp == "auto" {
+			fmtp = formatFromPath(p)
+		}
+		var inputs []string
+		var err error
+		if p == "-" {
+			inputs, err = readInputs(stdin, fmtp, column)
+		} else {
+			inputs, err = readFile(p, fmtp, column)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		all = append(all, inputs...)
+	}
+	return all, nil
+}
+
+func readFile(path, format, column string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readInputs(f, format, column)
+}
+
+func formatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return "csv"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	case ".txt":
+		return "lines"
+	}
+	return "auto"
+}
+
+// readInputs parses r as lines, csv or ndjson. "auto" treats input whose
+// first non-blank byte is '{' as NDJSON and anything else as lines.
+func readInputs(r io.Reader, format, column string) ([]string, error) {
+	br := bufio.NewReader(r)
+	if format == "auto" || format == "" {
+		format = sniffFormat(br)
+	}
+	switch format {
+	case "lines":
+		return readLines(br)
+	case "csv":
+		return readCSV(br, column)
+	case "ndjson":
+		return readNDJSON(br, column)
+	}
+	return nil, fmt.Errorf("unknown input format %q", format)
+}
+
+func sniffFormat(br *bufio.Reader) string {
+	peek, _ := br.Peek(512)
+	trimmed := bytes.TrimLeft(peek, " \t\r\n")
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		return "ndjson"
+	}
+	return "lines"
+}
+
+// readLines returns non-blank lines, skipping "#" comments.
+func readLines(r io.Reader) ([]string, error) {
+	var out []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return out, sc.Err()
+}
+
+// readCSV returns the values of column, located by name in the header row.
+func readCSV(r io.Reader, column string) ([]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	idx := -1
+	for i, h := range header {
+		if strings.EqualFold(strings.TrimSpace(h), column) {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return nil, fmt.Errorf("CSV column %q not found in header", column)
+	}
+	var out []string
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if idx < len(rec) {
+			if v := strings.TrimSpace(rec[idx]); v != "" {
+				out = append(out, v)
+			}
+		}
+	}
+}
+
+// readNDJSON returns field from each JSON object, or the value itself for
+// lines holding a bare JSON string.
+func readNDJSON(r io.Reader, field string) ([]string, error) {
+	var out []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var v interface{}
+		if err := json.Unmarshal(line, &v); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		switch t := v.(type) {
+		case string:
+			out = append(out, t)
+		case map[string]interface{}:
+			s, ok := t[field].(string)
+			if !ok {
+				return nil, fmt.Errorf("line %d: no string field %q", n, field)
+			}
+			out = append(out, s)
+		default:
+			return nil, fmt.Errorf("line %d: expected object or string", n)
+		}
+	}
+	return out, sc.Err()
+}
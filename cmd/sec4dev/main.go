@@ -0,0 +1,186 @@
+// Command sec4dev checks emails and IP addresses against the Sec4Dev API.
+//
+// Usage:
+//
+//	sec4dev email check [flags] EMAIL...
+//	sec4dev email bulk  [flags] [FILE...]
+//	sec4dev ip check    [flags] IP...
+//	sec4dev ip bulk     [flags] [FILE...]
+//
+// check reads its inputs from the arguments, or from stdin (one per line)
+// when none are given. bulk reads plain-text, CSV or NDJSON files, or stdin
+// when no file or "-" is given.
+//
+// Exit codes: 0 when nothing was flagged, 3 when at least one input was
+// flagged (disposable email; Tor, VPN, proxy or hosting IP), 1 when any
+// check failed, and 2 on usage errors.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/sec4dev/sec4dev-go"
+)
+
+const (
+	exitOK      = 0
+	exitError   = 1
+	exitUsage   = 2
+	exitFlagged = 3
+)
+
+const usage = `Usage:
+  sec4dev email check [flags] EMAIL...
+  sec4dev email bulk  [flags] [FILE...]
+  sec4dev ip check    [flags] IP...
+  sec4dev ip bulk     [flags] [FILE...]
+
+Run "sec4dev <kind> <command> -h" for flags.
+
+Exit codes: 0 clean, 3 at least one input flagged, 1 a check failed, 2 usage error.
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// options holds the flags shared by all subcommands.
+type options struct {
+	apiKey      string
+	baseURL     string
+	configPath  string
+	format      string
+	inputFormat string
+	column      string
+	concurrency int
+	adaptive    bool
+	rate        float64
+	retries     int
+	timeout     time.Duration
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) < 2 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	var k kind
+	switch args[0] {
+	case "email":
+		k = emailKind
+	case "ip":
+		k = ipKind
+	default:
+		fmt.Fprintf(stderr, "sec4dev: unknown kind %q\n\n%s", args[0], usage)
+		return exitUsage
+	}
+	cmd := args[1]
+	if cmd != "check" && cmd != "bulk" {
+		fmt.Fprintf(stderr, "sec4dev: unknown command %q\n\n%s", cmd, usage)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("sec4dev "+args[0]+" "+cmd, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var o options
+	fs.StringVar(&o.apiKey, "api-key", "", "API key (default $SEC4DEV_API_KEY, then the config file)")
+	fs.StringVar(&o.baseURL, "base-url", "", "API base URL, optionally followed by comma-separated fallbacks")
+	fs.StringVar(&o.configPath, "config", "", "config file (default $SEC4DEV_CONFIG_FILE, then $XDG_CONFIG_HOME/sec4dev/config.json)")
+	fs.StringVar(&o.format, "o", "table", "output format: table, json, ndjson or csv")
+	fs.IntVar(&o.retries, "retries", -1, "retries per request (default from config, else 3)")
+	fs.DurationVar(&o.timeout, "timeout", 0, "overall timeout (0 for none)")
+	fs.Float64Var(&o.rate, "rate", 0, "maximum requests per second (0 for unlimited)")
+	defaultConcurrency := 1
+	if cmd == "bulk" {
+		defaultConcurrency = 4
+		fs.StringVar(&o.inputFormat, "input-format", "auto", "input format: auto, lines, csv or ndjson")
+		fs.StringVar(&o.column, "column", k.name, "CSV column or NDJSON field holding the input")
+		fs.BoolVar(&o.adaptive, "adaptive", false, "tune concurrency from latency and rate limits, up to -concurrency")
+	}
+	fs.IntVar(&o.concurrency, "concurrency", defaultConcurrency, "concurrent requests")
+	if err := fs.Parse(args[2:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if o.concurrency < 1 {
+		fmt.Fprintln(stderr, "sec4dev: -concurrency must be at least 1")
+		return exitUsage
+	}
+	newWriter, ok := writers[o.format]
+	if !ok {
+		fmt.Fprintf(stderr, "sec4dev: unknown output format %q\n", o.format)
+		return exitUsage
+	}
+
+	var inputs []string
+	var err error
+	if cmd == "check" {
+		inputs = fs.Args()
+		if len(inputs) == 0 {
+			inputs, err = readInputs(stdin, "lines", "")
+		}
+	} else {
+		inputs, err = readFiles(fs.Args(), stdin, o.inputFormat, o.column)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "sec4dev: %v\n", err)
+		return exitUsage
+	}
+	if len(inputs) == 0 {
+		fmt.Fprintln(stderr, "sec4dev: no inputs")
+		return exitUsage
+	}
+
+	client, err := newClient(o)
+	if err != nil {
+		fmt.Fprintf(stderr, "sec4dev: %v\n", err)
+		return exitUsage
+	}
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+
+	w := newWriter(stdout, k)
+	sum := checkAll(ctx, client, k, inputs, o.concurrency, w)
+	if err := w.Close(); err != nil {
+		fmt.Fprintf(stderr, "sec4dev: %v\n", err)
+		return exitError
+	}
+	switch {
+	case sum.failed > 0:
+		fmt.Fprintf(stderr, "sec4dev: %d of %d checks failed\n", sum.failed, len(inputs))
+		return exitError
+	case sum.flagged > 0:
+		return exitFlagged
+	}
+	return exitOK
+}
+
+func newClient(o options) (*sec4dev.Client, error) {
+	cfg, err := loadConfig(o)
+	if err != nil {
+		return nil, err
+	}
+	var opts []sec4dev.ClientOption
+	if o.rate > 0 {
+		opts = append(opts, sec4dev.WithRequestRate(o.rate, 1))
+	}
+	if o.adaptive {
+		opts = append(opts, sec4dev.WithAdaptiveConcurrency(sec4dev.AdaptiveConfig{Max: o.concurrency, Initial: 1}))
+	}
+	return sec4dev.NewClientFromConfig(cfg, opts...)
+}
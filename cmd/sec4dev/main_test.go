@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sec4dev/sec4dev-go"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		email := body["email"]
+		domain := email[strings.Index(email, "@")+1:]
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"email": email, "domain": domain, "is_disposable": domain == "tempmail.com",
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRun_EmailCheckExitCodes(t *testing.T) {
+	server := newTestServer(t)
+	base := []string{"-api-key", "sec4_test", "-base-url", server.URL, "-config", os.DevNull, "-o", "csv"}
+
+	var out, errOut bytes.Buffer
+	args := append([]string{"email", "check"}, base...)
+	code := run(context.Background(), append(args, "a@gmail.com"), nil, &out, &errOut)
+	if code != exitOK {
+		t.Fatalf("code = %d, stderr = %s", code, errOut.String())
+	}
+
+	out.Reset()
+	code = run(context.Background(), append(args, "a@gmail.com", "b@tempmail.com"), nil, &out, &errOut)
+	if code != exitFlagged {
+		t.Fatalf("code = %d, want %d", code, exitFlagged)
+	}
+	want := "email,flagged,domain,disposable,error\n" +
+		"a@gmail.com,false,gmail.com,false,\n" +
+		"b@tempmail.com,true,tempmail.com,true,\n"
+	if out.String() != want {
+		t.Errorf("output =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	code = run(context.Background(), append(args, "not-an-email"), nil, &out, &errOut)
+	if code != exitError {
+		t.Errorf("code = %d, want %d", code, exitError)
+	}
+}
+
+func TestIPKind_FlagsDenylisted(t *testing.T) {
+	o, err := sec4dev.NewOverrides(sec4dev.OverrideConfig{DenyIPs: []string{"198.51.100.7"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, _ := sec4dev.NewClient("sec4_test", sec4dev.WithBaseURL("http://127.0.0.1:0"), sec4dev.WithOverrides(o))
+	r, err := ipKind.check(context.Background(), client, "198.51.100.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ipKind.flagged(r) {
+		t.Errorf("denylisted IP %+v not flagged", r)
+	}
+	if ipKind.flagged(&sec4dev.IPCheckResult{IP: "203.0.113.1", Confidence: 1}) {
+		t.Error("clean IP flagged")
+	}
+}
+
+func TestRun_EmailBulkNDJSONOrdered(t *testing.T) {
+	server := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "signups.csv")
+	csv := "id,email\n1,a@gmail.com\n2,b@tempmail.com\n3,c@gmail.com\n"
+	if err := os.WriteFile(path, []byte(csv), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut bytes.Buffer
+	args := []string{"email", "bulk", "-api-key", "sec4_test", "-base-url", server.URL,
+		"-config", os.DevNull, "-o", "ndjson", "-concurrency", "3", path}
+	if code := run(context.Background(), args, nil, &out, &errOut); code != exitFlagged {
+		t.Fatalf("code = %d, stderr = %s", code, errOut.String())
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("lines = %q", lines)
+	}
+	for i, want := range []string{"a@gmail.com", "b@tempmail.com", "c@gmail.com"} {
+		var rec struct{ Input string }
+		json.Unmarshal([]byte(lines[i]), &rec)
+		if rec.Input != want {
+			t.Errorf("line %d input = %q, want %q", i, rec.Input, want)
+		}
+	}
+}
+
+func TestRun_UsageErrors(t *testing.T) {
+	var out, errOut bytes.Buffer
+	for _, args := range [][]string{
+		{},
+		{"phone", "check"},
+		{"email", "verify"},
+		{"email", "check", "-o", "xml", "a@b.co"},
+	} {
+		if code := run(context.Background(), args, nil, &out, &errOut); code != exitUsage {
+			t.Errorf("run(%q) = %d, want %d", args, code, exitUsage)
+		}
+	}
+}
+
+func TestReadInputs_Formats(t *testing.T) {
+	cases := []struct {
+		format, in string
+		want       []string
+	}{
+		{"lines", "# header\n1.2.3.4\n\n 5.6.7.8 \n", []string{"1.2.3.4", "5.6.7.8"}},
+		{"auto", "{\"ip\":\"1.2.3.4\"}\n\"5.6.7.8\"\n", []string{"1.2.3.4", "5.6.7.8"}},
+		{"csv", "host,IP\na,1.2.3.4\nb,\n", []string{"1.2.3.4"}},
+	}
+	for _, tc := range cases {
+		got, err := readInputs(strings.NewReader(tc.in), tc.format, "ip")
+		if err != nil {
+			t.Fatalf("%s: %v", tc.format, err)
+		}
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("%s: got %q, want %q", tc.format, got, tc.want)
+		}
+	}
+	if _, err := readInputs(strings.NewReader("name\nx\n"), "csv", "ip"); err == nil {
+		t.Error("expected error for missing CSV column")
+	}
+}
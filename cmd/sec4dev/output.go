@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// recordWriter writes check records in one output format.
+type recordWriter interface {
+	Write(rec record) error
+	Close() error
+}
+
+var writers = map[string]func(w io.Writer, k kind) recordWriter{
+	"table":  newTableWriter,
+	"json":   newJSONWriter,
+	"ndjson": newNDJSONWriter,
+	"csv":    newCSVWriter,
+}
+
+// rowFor returns the flat column values for rec: input, flagged, the kind's
+// columns, then error.
+func rowFor(k kind, rec record) []string {
+	row := []string{rec.Input, strconv.FormatBool(rec.Flagged)}
+	if rec.Result != nil {
+		row = append(row, k.row(rec.Result)...)
+	} else {
+		row = append(row, make([]string, len(k.columns))...)
+	}
+	return append(row, rec.Error)
+}
+
+func headerFor(k kind) []string {
+	h := append([]string{k.name, "flagged"}, k.columns...)
+	return append(h, "error")
+}
+
+type tableWriter struct {
+	tw *tabwriter.Writer
+	k  kind
+}
+
+func newTableWriter(w io.Writer, k kind) recordWriter {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	h := headerFor(k)
+	for i := range h {
+		h[i] = strings.ToUpper(h[i])
+	}
+	io.WriteString(tw, strings.Join(h, "\t")+"\n")
+	return &tableWriter{tw: tw, k: k}
+}
+
+func (t *tableWriter) Write(rec record) error {
+	row := rowFor(t.k, rec)
+	for i, v := range row {
+		if v == "" {
+			row[i] = "-"
+		}
+	}
+	_, err := io.WriteString(t.tw, strings.Join(row, "\t")+"\n")
+	return err
+}
+
+func (t *tableWriter) Close() error { return t.tw.Flush() }
+
+type jsonWriter struct {
+	w    io.Writer
+	recs []record
+}
+
+func newJSONWriter(w io.Writer, _ kind) recordWriter { return &jsonWriter{w: w, recs: []record{}} }
+
+func (j *jsonWriter) Write(rec record) error {
+	j.recs = append(j.recs, rec)
+	return nil
+}
+
+func (j *jsonWriter) Close() error {
+	enc := json.NewEncoder(j.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(j.recs)
+}
+
+type ndjsonWriter struct{ enc *json.Encoder }
+
+func newNDJSONWriter(w io.Writer, _ kind) recordWriter { return &ndjsonWriter{enc: json.NewEncoder(w)} }
+
+func (n *ndjsonWriter) Write(rec record) error { return n.enc.Encode(rec) }
+
+func (n *ndjsonWriter) Close() error { return nil }
+
+type csvWriter struct {
+	cw *csv.Writer
+	k  kind
+}
+
+func newCSVWriter(w io.Writer, k kind) recordWriter {
+	cw := csv.NewWriter(w)
+	cw.Write(headerFor(k))
+	return &csvWriter{cw: cw, k: k}
+}
+
+func (c *csvWriter) Write(rec record) error { return c.cw.Write(rowFor(c.k, rec)) }
+
+func (c *csvWriter) Close() error {
+	c.cw.Flush()
+	return c.cw.Error()
+}
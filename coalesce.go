@@ -0,0 +1,77 @@
+package sec4dev
+
+import (
+	"context"
+	"errors"
+)
+
+// WithCoalescing makes concurrent identical calls that miss the cache share
+// a single API request. Each caller still honours its own context: if the
+// call it joined ends because that caller's context was cancelled, it makes
+// the request itself.
+func WithCoalescing() ClientOption {
+	return func(c *Client) {
+		c.coalescing = true
+	}
+}
+
+// flight is an API request that identical calls can wait on.
+type flight struct {
+	done chan struct{}
+	resp *Response
+	err  error
+}
+
+// coalesce runs fetch for req, or waits for an identical request already in
+// flight and returns a copy of its response.
+func (c *Client) coalesce(ctx context.Context, req *Request, fetch func(context.Context) (*Response, error)) (*Response, error) {
+	if !c.coalescing || req.refresh {
+		return fetch(ctx)
+	}
+	key := cacheKey(req)
+	for {
+		c.mu.Lock()
+		f, ok := c.flights[key]
+		if !ok {
+			f = &flight{done: make(chan struct{})}
+			if c.flights == nil {
+				c.flights = make(map[string]*flight)
+			}
+			c.flights[key] = f
+			c.mu.Unlock()
+
+			f.resp, f.err = fetch(ctx)
+			c.mu.Lock()
+			delete(c.flights, key)
+			c.mu.Unlock()
+			close(f.done)
+			return f.share()
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if isContextErr(f.err) && ctx.Err() == nil {
+			continue
+		}
+		c.metrics.observeCoalesced(req.Path)
+		return f.share()
+	}
+}
+
+// share returns the flight's outcome with a copy of its response, which
+// each caller decodes for itself.
+func (f *flight) share() (*Response, error) {
+	if f.resp == nil {
+		return nil, f.err
+	}
+	resp := *f.resp
+	return &resp, f.err
+}
+
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
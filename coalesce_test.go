@@ -0,0 +1,104 @@
+package sec4dev
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCoalescing_SharesInFlightRequest(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(30 * time.Millisecond)
+		emailHandler(w, r)
+	}))
+	defer server.Close()
+	m := NewMetrics()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithCoalescing(), WithMetrics(m))
+
+	var wg sync.WaitGroup
+	results := make([]*EmailCheckResult, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r, err := client.Email().Check(context.Background(), "user@gmail.com")
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = r
+		}(i)
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("upstream calls = %d, want 1", n)
+	}
+	for i, r := range results[1:] {
+		if r == nil || r == results[0] || r.Email != "user@gmail.com" {
+			t.Errorf("results[%d] = %+v, want its own copy", i+1, r)
+		}
+	}
+	if got := m.coalesced["/email/check"]; got != 4 {
+		t.Errorf("coalesced = %d, want 4", got)
+	}
+
+	// Different inputs are not coalesced.
+	atomic.StoreInt32(&calls, 0)
+	for _, e := range []string{"a@gmail.com", "b@gmail.com"} {
+		wg.Add(1)
+		go func(e string) {
+			defer wg.Done()
+			client.Email().Check(context.Background(), e)
+		}(e)
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("upstream calls = %d, want 2", n)
+	}
+}
+
+func TestCoalescing_LeaderCancelled(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(30 * time.Millisecond)
+		emailHandler(w, r)
+	}))
+	defer server.Close()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithCoalescing(), WithRetries(0))
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error)
+	go func() {
+		_, err := client.Email().Check(leaderCtx, "user@gmail.com")
+		leader <- err
+	}()
+	waitFor(t, func() bool { return atomic.LoadInt32(&calls) == 1 })
+
+	follower := make(chan error)
+	go func() {
+		_, err := client.Email().Check(context.Background(), "user@gmail.com")
+		follower <- err
+	}()
+	waitFor(t, func() bool {
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		return len(client.flights) == 1
+	})
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+	if err := <-leader; err == nil {
+		t.Error("leader succeeded after cancel")
+	}
+	if err := <-follower; err != nil {
+		t.Errorf("follower: %v, want it to retry on its own", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("upstream calls = %d, want 2", n)
+	}
+}
@@ -0,0 +1,60 @@
+package sec4dev
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxResponseBytes caps a decoded response body.
+const maxResponseBytes = 64 << 20
+
+// WithRequestCompression gzips request bodies of at least threshold bytes
+// and sends them with Content-Encoding: gzip. Responses are always requested
+// with Accept-Encoding: gzip and decoded transparently.
+func WithRequestCompression(threshold int) ClientOption {
+	return func(c *Client) {
+		c.compressThreshold = threshold
+	}
+}
+
+// encodeRequestBody returns b, gzipped when it reaches the configured threshold.
+func (c *Client) encodeRequestBody(b []byte) (body []byte, gzipped bool, err error) {
+	if c.compressThreshold <= 0 || len(b) < c.compressThreshold {
+		return b, false, nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return nil, false, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), true, nil
+}
+
+// readResponseBody reads resp.Body, decoding gzip content.
+func readResponseBody(resp *http.Response) ([]byte, error) {
+	var r io.Reader = resp.Body
+	if strings.EqualFold(strings.TrimSpace(resp.Header.Get("Content-Encoding")), "gzip") {
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+	}
+	out, err := io.ReadAll(io.LimitReader(r, maxResponseBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > maxResponseBytes {
+		return nil, baseError("Response body too large", resp.StatusCode, nil)
+	}
+	return out, nil
+}
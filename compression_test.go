@@ -0,0 +1,69 @@
+package sec4dev
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCompression_GzipRequestAndResponse(t *testing.T) {
+	var gotEncoding, gotAccept, gotEmail string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotEncoding = r.Header.Get("Content-Encoding")
+		gotAccept = r.Header.Get("Accept-Encoding")
+		body := r.Body
+		if gotEncoding == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			body = zr
+		}
+		var req map[string]string
+		json.NewDecoder(body).Decode(&req)
+		gotEmail = req["email"]
+
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		json.NewEncoder(zw).Encode(map[string]interface{}{
+			"email": gotEmail, "domain": "tempmail.com", "is_disposable": true,
+		})
+		zw.Close()
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL+"/api/v1"), WithHTTPClient(server.Client()),
+		WithRequestCompression(16))
+	result, err := client.Email().Check(context.Background(), "someone.long@tempmail.com")
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if gotEncoding != "gzip" || gotAccept != "gzip" || gotEmail != "someone.long@tempmail.com" {
+		t.Errorf("Content-Encoding = %q, Accept-Encoding = %q, email = %q", gotEncoding, gotAccept, gotEmail)
+	}
+	if !result.IsDisposable || result.Email != "someone.long@tempmail.com" {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestCompression_BelowThresholdUncompressed(t *testing.T) {
+	var gotEncoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotEncoding = r.Header.Get("Content-Encoding")
+		emailHandler(w, r)
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL+"/api/v1"), WithHTTPClient(server.Client()),
+		WithRequestCompression(1024))
+	if _, err := client.Email().Check(context.Background(), "user@gmail.com"); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if gotEncoding != "" {
+		t.Errorf("Content-Encoding = %q, want none", gotEncoding)
+	}
+}
@@ -0,0 +1,195 @@
+package sec4dev
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables read by LoadConfig and NewClientFromEnv.
+const (
+	EnvAPIKey             = "SEC4DEV_API_KEY"
+	EnvAPIKeyFile         = "SEC4DEV_API_KEY_FILE"
+	EnvBaseURL            = "SEC4DEV_BASE_URL"
+	EnvRetries            = "SEC4DEV_RETRIES"
+	EnvRetryDelayMs       = "SEC4DEV_RETRY_DELAY_MS"
+	EnvTimeoutMs          = "SEC4DEV_TIMEOUT_MS"
+	EnvAttemptTimeoutMs   = "SEC4DEV_ATTEMPT_TIMEOUT_MS"
+	EnvOperationTimeoutMs = "SEC4DEV_OPERATION_TIMEOUT_MS"
+	EnvProxyURL           = "SEC4DEV_PROXY_URL"
+	EnvCacheTTLSeconds    = "SEC4DEV_CACHE_TTL_SECONDS"
+	EnvCacheMaxEntries    = "SEC4DEV_CACHE_MAX_ENTRIES"
+	EnvCacheFile          = "SEC4DEV_CACHE_FILE"
+	EnvConfigFile         = "SEC4DEV_CONFIG_FILE"
+)
+
+// Config holds client settings loaded from a JSON file or the environment.
+// Nil and empty fields leave the client default in place. BaseURL may list
+// fallback URLs after the primary, separated by commas.
+type Config struct {
+	APIKey             string `json:"api_key,omitempty"`
+	APIKeyFile         string `json:"api_key_file,omitempty"`
+	BaseURL            string `json:"base_url,omitempty"`
+	Retries            *int   `json:"retries,omitempty"`
+	RetryDelayMs       *int   `json:"retry_delay_ms,omitempty"`
+	TimeoutMs          *int   `json:"timeout_ms,omitempty"` // 0 disables the timeout
+	AttemptTimeoutMs   *int   `json:"attempt_timeout_ms,omitempty"`
+	OperationTimeoutMs *int   `json:"operation_timeout_ms,omitempty"`
+	ProxyURL           string `json:"proxy_url,omitempty"`
+	CacheTTLSeconds    *int   `json:"cache_ttl_seconds,omitempty"`
+	CacheMaxEntries    *int   `json:"cache_max_entries,omitempty"`
+	// CacheFile, with CacheTTLSeconds, caches results in a FileCache at
+	// this path instead of in memory. CacheMaxEntries applies to it too.
+	CacheFile string `json:"cache_file,omitempty"`
+}
+
+// ReadConfigFile reads a JSON config file.
+func ReadConfigFile(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{}
+	if len(strings.TrimSpace(string(b))) == 0 {
+		return cfg, nil
+	}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, &ValidationError{baseError(fmt.Sprintf("Invalid config file %s: %v", path, err), 422, nil)}
+	}
+	return cfg, nil
+}
+
+// LoadConfig reads the file named by SEC4DEV_CONFIG_FILE, if set, and then
+// applies the SEC4DEV_* environment variables over it.
+func LoadConfig() (*Config, error) {
+	cfg := &Config{}
+	if path := os.Getenv(EnvConfigFile); path != "" {
+		var err error
+		if cfg, err = ReadConfigFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.LoadEnv(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadEnv overrides cfg with any SEC4DEV_* environment variables that are set.
+func (cfg *Config) LoadEnv() error {
+	if v := os.Getenv(EnvAPIKey); v != "" {
+		cfg.APIKey = v
+	}
+	if v := os.Getenv(EnvAPIKeyFile); v != "" {
+		cfg.APIKeyFile = v
+	}
+	if v := os.Getenv(EnvBaseURL); v != "" {
+		cfg.BaseURL = v
+	}
+	if v := os.Getenv(EnvProxyURL); v != "" {
+		cfg.ProxyURL = v
+	}
+	if v := os.Getenv(EnvCacheFile); v != "" {
+		cfg.CacheFile = v
+	}
+	for _, f := range []struct {
+		name string
+		dst  **int
+	}{
+		{EnvRetries, &cfg.Retries},
+		{EnvRetryDelayMs, &cfg.RetryDelayMs},
+		{EnvTimeoutMs, &cfg.TimeoutMs},
+		{EnvAttemptTimeoutMs, &cfg.AttemptTimeoutMs},
+		{EnvOperationTimeoutMs, &cfg.OperationTimeoutMs},
+		{EnvCacheTTLSeconds, &cfg.CacheTTLSeconds},
+		{EnvCacheMaxEntries, &cfg.CacheMaxEntries},
+	} {
+		v := os.Getenv(f.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 0 {
+			return &ValidationError{baseError(fmt.Sprintf("%s must be a non-negative integer", f.name), 422, nil)}
+		}
+		*f.dst = &n
+	}
+	return nil
+}
+
+// Options converts cfg to client options.
+func (cfg *Config) Options() ([]ClientOption, error) {
+	var opts []ClientOption
+	if urls := splitBaseURLs(cfg.BaseURL); len(urls) > 0 {
+		opts = append(opts, WithBaseURL(urls[0], urls[1:]...))
+	}
+	if cfg.Retries != nil {
+		opts = append(opts, WithRetries(*cfg.Retries))
+	}
+	if cfg.RetryDelayMs != nil {
+		opts = append(opts, WithRetryDelay(*cfg.RetryDelayMs))
+	}
+	if cfg.TimeoutMs != nil {
+		opts = append(opts, WithTimeout(time.Duration(*cfg.TimeoutMs)*time.Millisecond))
+	}
+	if cfg.AttemptTimeoutMs != nil && *cfg.AttemptTimeoutMs > 0 {
+		opts = append(opts, WithAttemptTimeout(time.Duration(*cfg.AttemptTimeoutMs)*time.Millisecond))
+	}
+	if cfg.OperationTimeoutMs != nil && *cfg.OperationTimeoutMs > 0 {
+		opts = append(opts, WithOperationTimeout(time.Duration(*cfg.OperationTimeoutMs)*time.Millisecond))
+	}
+	if cfg.ProxyURL != "" {
+		u, err := url.Parse(cfg.ProxyURL)
+		if err != nil || u.Host == "" {
+			return nil, &ValidationError{baseError("Invalid proxy URL "+cfg.ProxyURL, 422, nil)}
+		}
+		opts = append(opts, WithProxy(u))
+	}
+	if cfg.CacheTTLSeconds != nil && *cfg.CacheTTLSeconds > 0 {
+		maxEntries := 0
+		if cfg.CacheMaxEntries != nil {
+			maxEntries = *cfg.CacheMaxEntries
+		}
+		ttl := time.Duration(*cfg.CacheTTLSeconds) * time.Second
+		var cache Cache = NewMemoryCache(maxEntries)
+		if cfg.CacheFile != "" {
+			fc, err := NewFileCache(cfg.CacheFile, FileCacheConfig{MaxEntries: maxEntries})
+			if err != nil {
+				return nil, err
+			}
+			cache = fc
+		}
+		opts = append(opts, WithCache(cache, ttl))
+	}
+	return opts, nil
+}
+
+// NewClientFromConfig creates a client from cfg. Explicit opts are applied
+// after the config and take precedence over it. If APIKey is empty and
+// APIKeyFile is set, the key is read from that file and reloaded when it
+// changes.
+func NewClientFromConfig(cfg *Config, opts ...ClientOption) (*Client, error) {
+	cfgOpts, err := cfg.Options()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(cfgOpts, opts...)
+	if cfg.APIKey == "" && cfg.APIKeyFile != "" {
+		return NewClientWithCredentials(NewFileCredentials(cfg.APIKeyFile, 0), opts...)
+	}
+	return NewClient(cfg.APIKey, opts...)
+}
+
+// NewClientFromEnv creates a client from LoadConfig. Precedence, highest
+// first: explicit opts, environment variables, the config file, defaults.
+func NewClientFromEnv(opts ...ClientOption) (*Client, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return NewClientFromConfig(cfg, opts...)
+}
@@ -0,0 +1,82 @@
+package sec4dev
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewClientFromEnv_Precedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sec4dev.json")
+	file := `{"api_key": "sec4_file", "base_url": "https://file.example.com/v1", "retries": 5, "retry_delay_ms": 250}`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvConfigFile, path)
+	t.Setenv(EnvAPIKey, "sec4_env")
+	t.Setenv(EnvRetries, "2")
+	t.Setenv(EnvTimeoutMs, "1500")
+
+	client, err := NewClientFromEnv(WithRetries(7))
+	if err != nil {
+		t.Fatalf("NewClientFromEnv: %v", err)
+	}
+	if client.APIKey != "sec4_env" {
+		t.Errorf("APIKey = %q, want env value", client.APIKey)
+	}
+	if client.BaseURL != "https://file.example.com/v1" {
+		t.Errorf("BaseURL = %q, want file value", client.BaseURL)
+	}
+	if client.Retries != 7 {
+		t.Errorf("Retries = %d, want explicit option value", client.Retries)
+	}
+	if client.RetryDelayMs != 250 {
+		t.Errorf("RetryDelayMs = %d, want file value", client.RetryDelayMs)
+	}
+	if client.timeout != 1500*time.Millisecond {
+		t.Errorf("timeout = %v", client.timeout)
+	}
+}
+
+func TestLoadConfig_ProxyAndCache(t *testing.T) {
+	t.Setenv(EnvAPIKey, "sec4_env")
+	t.Setenv(EnvProxyURL, "http://proxy.internal:3128")
+	t.Setenv(EnvCacheTTLSeconds, "300")
+	t.Setenv(EnvCacheMaxEntries, "10")
+
+	client, err := NewClientFromEnv()
+	if err != nil {
+		t.Fatalf("NewClientFromEnv: %v", err)
+	}
+	if client.proxyURL == nil || client.proxyURL.Host != "proxy.internal:3128" {
+		t.Errorf("proxy = %v", client.proxyURL)
+	}
+	if _, ok := client.cache.(*MemoryCache); !ok || client.cacheTTL != 5*time.Minute {
+		t.Errorf("cache = %T, ttl = %v", client.cache, client.cacheTTL)
+	}
+
+	t.Setenv(EnvCacheFile, filepath.Join(t.TempDir(), "results.log"))
+	client, err = NewClientFromEnv()
+	if err != nil {
+		t.Fatalf("NewClientFromEnv: %v", err)
+	}
+	if fc, ok := client.cache.(*FileCache); !ok || fc.maxItems != 10 {
+		t.Errorf("cache = %T %+v, want *FileCache holding at most 10 entries", client.cache, client.cache)
+	} else {
+		fc.Close()
+	}
+}
+
+func TestLoadConfig_RejectsInvalid(t *testing.T) {
+	t.Setenv(EnvRetries, "many")
+	if _, err := LoadConfig(); err == nil {
+		t.Error("expected error for non-numeric retries")
+	}
+
+	path := filepath.Join(t.TempDir(), "bad.json")
+	os.WriteFile(path, []byte("{not json"), 0o600)
+	if _, err := ReadConfigFile(path); err == nil {
+		t.Error("expected error for malformed file")
+	}
+}
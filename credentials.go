@@ -0,0 +1,238 @@
+package sec4dev
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CredentialProvider supplies the API key. It is consulted on every call, so
+// keys can be rotated without recreating the client.
+type CredentialProvider interface {
+	APIKey(ctx context.Context) (string, error)
+}
+
+// CredentialRefresher is implemented by providers that can reload their key.
+// When the API rejects a key with 401, the client calls Refresh and retries
+// the call if the provider then returns a key it has not tried yet.
+type CredentialRefresher interface {
+	Refresh(ctx context.Context) error
+}
+
+// WithCredentials makes the client fetch its API key from p on every call
+// instead of using the fixed Client.APIKey.
+func WithCredentials(p CredentialProvider) ClientOption {
+	return func(c *Client) {
+		c.credentials = p
+	}
+}
+
+// NewClientWithCredentials creates a client whose API key comes from p.
+func NewClientWithCredentials(p CredentialProvider, opts ...ClientOption) (*Client, error) {
+	if p == nil {
+		return nil, &ValidationError{baseError("Credential provider is required", 422, nil)}
+	}
+	c := newClient("", opts)
+	if c.credentials == nil {
+		c.credentials = p
+	}
+	return c, nil
+}
+
+// StaticCredentials is a fixed API key.
+type StaticCredentials string
+
+// APIKey returns the key.
+func (s StaticCredentials) APIKey(ctx context.Context) (string, error) {
+	return string(s), nil
+}
+
+// EnvCredentials reads the API key from the named environment variable on
+// every call.
+type EnvCredentials string
+
+// APIKey returns the current value of the environment variable.
+func (e EnvCredentials) APIKey(ctx context.Context) (string, error) {
+	v := strings.TrimSpace(os.Getenv(string(e)))
+	if v == "" {
+		return "", &ValidationError{baseError("Environment variable "+string(e)+" is not set", 422, nil)}
+	}
+	return v, nil
+}
+
+// FileCredentials reads the API key from a file, such as a mounted secret,
+// and reloads it when the file changes.
+type FileCredentials struct {
+	path     string
+	interval time.Duration
+
+	mu      sync.Mutex
+	key     string
+	modTime time.Time
+	size    int64
+	checked time.Time
+}
+
+// NewFileCredentials watches path for a new key at most once per
+// checkInterval (default: 1s).
+func NewFileCredentials(path string, checkInterval time.Duration) *FileCredentials {
+	if checkInterval <= 0 {
+		checkInterval = time.Second
+	}
+	return &FileCredentials{path: path, interval: checkInterval}
+}
+
+// APIKey returns the key from the file, reloading it if it changed.
+func (f *FileCredentials) APIKey(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.key != "" && time.Since(f.checked) < f.interval {
+		return f.key, nil
+	}
+	return f.loadLocked(false)
+}
+
+// Refresh rereads the file immediately.
+func (f *FileCredentials) Refresh(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err := f.loadLocked(true)
+	return err
+}
+
+func (f *FileCredentials) loadLocked(force bool) (string, error) {
+	fi, err := os.Stat(f.path)
+	if err != nil {
+		return "", err
+	}
+	f.checked = time.Now()
+	if !force && f.key != "" && fi.ModTime().Equal(f.modTime) && fi.Size() == f.size {
+		return f.key, nil
+	}
+	b, err := os.ReadFile(f.path)
+	if err != nil {
+		return "", err
+	}
+	key := strings.TrimSpace(string(b))
+	if key == "" {
+		return "", &ValidationError{baseError("Credential file "+f.path+" is empty", 422, nil)}
+	}
+	f.key, f.modTime, f.size = key, fi.ModTime(), fi.Size()
+	return key, nil
+}
+
+// CallbackCredentials fetches the API key with a callback, for example from a
+// secrets manager, and caches it for a TTL.
+type CallbackCredentials struct {
+	fn  func(ctx context.Context) (string, error)
+	ttl time.Duration
+
+	mu      sync.Mutex
+	key     string
+	fetched time.Time
+}
+
+// NewCallbackCredentials caches the key returned by fn for ttl. A ttl of zero
+// calls fn on every request.
+func NewCallbackCredentials(fn func(ctx context.Context) (string, error), ttl time.Duration) *CallbackCredentials {
+	return &CallbackCredentials{fn: fn, ttl: ttl}
+}
+
+// APIKey returns the cached key, calling the callback when it has expired.
+func (cb *CallbackCredentials) APIKey(ctx context.Context) (string, error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.key != "" && time.Since(cb.fetched) < cb.ttl {
+		return cb.key, nil
+	}
+	key, err := cb.fn(ctx)
+	if err != nil {
+		return "", err
+	}
+	cb.key, cb.fetched = strings.TrimSpace(key), time.Now()
+	return cb.key, nil
+}
+
+// Refresh drops the cached key so the next call invokes the callback.
+func (cb *CallbackCredentials) Refresh(ctx context.Context) error {
+	cb.mu.Lock()
+	cb.key = ""
+	cb.mu.Unlock()
+	return nil
+}
+
+// apiKey returns the key to use for the next call.
+func (c *Client) apiKey(ctx context.Context) (string, error) {
+	if c.credentials == nil {
+		return c.APIKey, nil
+	}
+	key, err := c.credentials.APIKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(key, "sec4_") {
+		return "", &ValidationError{baseError("API key must start with sec4_", 422, nil)}
+	}
+	return key, nil
+}
+
+// nextAPIKey returns a different key to retry with after old failed with
+// err. On 401 the provider is refreshed first; on 402 and 403 a provider
+// such as KeyPool may have moved on to another key. A 429 that exhausted a
+// pooled key moves on only when another pooled key is available.
+func (c *Client) nextAPIKey(ctx context.Context, old string, err error) (string, bool) {
+	if c.credentials == nil {
+		return "", false
+	}
+	switch e := err.(type) {
+	case *AuthenticationError:
+		if r, ok := c.credentials.(CredentialRefresher); ok {
+			if err := r.Refresh(ctx); err != nil {
+				return "", false
+			}
+		}
+	case *PaymentRequiredError, *ForbiddenError:
+	case *RateLimitError:
+		if e.Limit == 0 || e.Remaining > 0 || !c.canFailOver(old) {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	key, kerr := c.apiKey(ctx)
+	if kerr != nil || key == old {
+		return "", false
+	}
+	return key, true
+}
+
+// canFailOver reports whether the credentials are a KeyPool with a key other
+// than key available now.
+func (c *Client) canFailOver(key string) bool {
+	p, ok := c.credentials.(*KeyPool)
+	return ok && p.availableExcept(key)
+}
+
+// keyName labels key for per-key quota tracking: its KeyPool name, or ""
+// for any other credential.
+func (c *Client) keyName(key string) string {
+	if p, ok := c.credentials.(*KeyPool); ok {
+		return p.name(key)
+	}
+	return ""
+}
+
+// observeKey reports the outcome of a call made with key to the provider.
+func (c *Client) observeKey(key string, resp *Response, err error) {
+	o, ok := c.credentials.(CredentialObserver)
+	if !ok {
+		return
+	}
+	var rl RateLimitInfo
+	if resp != nil {
+		rl = resp.RateLimit
+	}
+	o.ObserveKey(key, rl, err)
+}
@@ -0,0 +1,82 @@
+package sec4dev
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCredentials_RetriesWithRefreshedKeyOn401(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("X-API-Key")
+		keys = append(keys, key)
+		if key != "sec4_new" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"detail": "Invalid API key"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"email": "user@gmail.com", "domain": "gmail.com", "is_disposable": false,
+		})
+	}))
+	defer server.Close()
+
+	current := "sec4_old"
+	fetches := 0
+	provider := NewCallbackCredentials(func(ctx context.Context) (string, error) {
+		fetches++
+		return current, nil
+	}, time.Hour)
+	client, err := NewClientWithCredentials(provider, WithBaseURL(server.URL+"/api/v1"), WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatalf("NewClientWithCredentials: %v", err)
+	}
+
+	if _, err := client.Email().Check(context.Background(), "user@gmail.com"); err == nil {
+		t.Fatal("expected AuthenticationError while provider returns the old key")
+	}
+
+	// The rotated key is only picked up through the refresh after the 401,
+	// since the provider still caches the old one.
+	current = "sec4_new"
+	if _, err := client.Email().Check(context.Background(), "user@gmail.com"); err != nil {
+		t.Fatalf("Check after rotation: %v", err)
+	}
+	if fetches != 3 {
+		t.Errorf("fetches = %d, want 3", fetches)
+	}
+	if got := keys[len(keys)-2:]; got[0] != "sec4_old" || got[1] != "sec4_new" {
+		t.Errorf("keys = %v", keys)
+	}
+}
+
+func TestFileCredentials_ReloadsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	os.WriteFile(path, []byte("sec4_first\n"), 0o600)
+	fc := NewFileCredentials(path, time.Nanosecond)
+	ctx := context.Background()
+
+	if k, err := fc.APIKey(ctx); err != nil || k != "sec4_first" {
+		t.Fatalf("APIKey = %q, %v", k, err)
+	}
+	os.WriteFile(path, []byte("sec4_second_key"), 0o600)
+	fc.Refresh(ctx)
+	if k, _ := fc.APIKey(ctx); k != "sec4_second_key" {
+		t.Errorf("APIKey after change = %q", k)
+	}
+}
+
+func TestCredentials_RejectsInvalidKey(t *testing.T) {
+	t.Setenv("SEC4DEV_TEST_KEY", "bogus")
+	client, _ := NewClientWithCredentials(EnvCredentials("SEC4DEV_TEST_KEY"))
+	_, err := client.IP().Check(context.Background(), "203.0.113.42")
+	if _, ok := err.(*ValidationError); !ok {
+		t.Errorf("expected ValidationError, got %T (%v)", err, err)
+	}
+}
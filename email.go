@@ -3,6 +3,7 @@ package sec4dev
 import (
 	"context"
 	"encoding/json"
+	"net/http"
 	"strings"
 )
 
@@ -16,21 +17,22 @@ func (s *EmailService) Check(ctx context.Context, email string) (*EmailCheckResu
 	if err := ValidateEmail(email); err != nil {
 		return nil, err
 	}
-	path := "/email/check"
-	body := map[string]string{"email": strings.TrimSpace(email)}
-	var onRateLimit func(RateLimitInfo)
-	if s.client.onRateLimit != nil {
-		onRateLimit = func(r RateLimitInfo) {
-			s.client.rateLimit = r
-			s.client.onRateLimit(r)
-		}
-	} else {
-		onRateLimit = func(r RateLimitInfo) { s.client.rateLimit = r }
+	if r, ok := s.client.overrides.email(email); ok {
+		return r, nil
 	}
-	out, _, err := s.client.postWithRetry(ctx, path, body, onRateLimit)
+	req := &Request{
+		Method: http.MethodPost,
+		Path:   "/email/check",
+		Body:   map[string]string{"email": strings.TrimSpace(email)},
+	}
+	resp, err := s.client.call(ctx, req, decodeEmailCheckResult)
 	if err != nil {
 		return nil, err
 	}
+	return resultAs[EmailCheckResult](resp)
+}
+
+func decodeEmailCheckResult(out []byte) (interface{}, error) {
 	var raw struct {
 		Email        string `json:"email"`
 		Domain       string `json:"domain"`
@@ -0,0 +1,153 @@
+package sec4dev
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// endpointFailures is how many consecutive failures mark an endpoint
+	// unhealthy.
+	endpointFailures     = 3
+	defaultProbeInterval = 30 * time.Second
+)
+
+// WithEndpointProbeInterval sets how long an unhealthy base URL is skipped
+// before one request is let through to probe it (default: 30s).
+func WithEndpointProbeInterval(d time.Duration) ClientOption {
+	return func(c *Client) {
+		if c.endpoints == nil {
+			c.endpoints = &endpoints{}
+		}
+		c.endpoints.probeInterval = d
+	}
+}
+
+// endpoints tracks the health of the base URLs. BaseURL is the primary;
+// fallbacks are tried in order after it.
+type endpoints struct {
+	mu            sync.Mutex
+	fallbacks     []string
+	probeInterval time.Duration
+	health        map[string]*endpointHealth
+}
+
+type endpointHealth struct {
+	failures  int
+	downUntil time.Time
+	lastErr   string
+}
+
+// EndpointStatus is the health of one base URL, as seen by the client.
+type EndpointStatus struct {
+	URL     string
+	Healthy bool
+	// ConsecutiveFailures counts network errors and 5xx responses since
+	// the last success.
+	ConsecutiveFailures int
+	LastError           string
+}
+
+// Endpoints reports the health of the client's base URLs in failover order.
+func (c *Client) Endpoints() []EndpointStatus {
+	urls := c.baseURLs()
+	out := make([]EndpointStatus, len(urls))
+	e := c.endpoints
+	for i, u := range urls {
+		out[i] = EndpointStatus{URL: u, Healthy: true}
+		if e == nil {
+			continue
+		}
+		e.mu.Lock()
+		if h := e.health[u]; h != nil {
+			out[i].Healthy = h.failures < endpointFailures
+			out[i].ConsecutiveFailures = h.failures
+			out[i].LastError = h.lastErr
+		}
+		e.mu.Unlock()
+	}
+	return out
+}
+
+func (c *Client) baseURLs() []string {
+	if c.endpoints == nil {
+		return []string{c.BaseURL}
+	}
+	return append([]string{c.BaseURL}, c.endpoints.fallbacks...)
+}
+
+// endpointOrder returns the base URLs to try for one attempt: healthy ones
+// and unhealthy ones due for a probe, in configured order, followed by the
+// rest as a last resort.
+func (c *Client) endpointOrder() []string {
+	urls := c.baseURLs()
+	e := c.endpoints
+	if len(urls) == 1 {
+		return urls
+	}
+	interval := e.probeInterval
+	if interval <= 0 {
+		interval = defaultProbeInterval
+	}
+	now := time.Now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var up, down []string
+	for _, u := range urls {
+		h := e.health[u]
+		switch {
+		case h == nil || h.failures < endpointFailures:
+			up = append(up, u)
+		case !now.Before(h.downUntil):
+			// Let this request probe it; others skip it until the next
+			// interval.
+			h.downUntil = now.Add(interval)
+			up = append(up, u)
+		default:
+			down = append(down, u)
+		}
+	}
+	return append(up, down...)
+}
+
+// report records the outcome of a request to a base URL.
+func (e *endpoints) report(url string, failure error) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if failure == nil {
+		delete(e.health, url)
+		return
+	}
+	if e.health == nil {
+		e.health = make(map[string]*endpointHealth)
+	}
+	h := e.health[url]
+	if h == nil {
+		h = &endpointHealth{}
+		e.health[url] = h
+	}
+	h.failures++
+	h.lastErr = failure.Error()
+	if h.failures == endpointFailures {
+		interval := e.probeInterval
+		if interval <= 0 {
+			interval = defaultProbeInterval
+		}
+		h.downUntil = time.Now().Add(interval)
+	}
+}
+
+// splitBaseURLs splits a comma-separated list of base URLs.
+func splitBaseURLs(s string) []string {
+	var out []string
+	for _, u := range strings.Split(s, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			out = append(out, u)
+		}
+	}
+	return out
+}
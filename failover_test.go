@@ -0,0 +1,92 @@
+package sec4dev
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFailover_5xxAndHealth(t *testing.T) {
+	var primaryDown int32 = 1
+	var primaryHits, backupHits int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&primaryHits, 1)
+		if atomic.LoadInt32(&primaryDown) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		emailHandler(w, r)
+	}))
+	defer primary.Close()
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&backupHits, 1)
+		emailHandler(w, r)
+	}))
+	defer backup.Close()
+
+	client, _ := NewClient("sec4_test", WithBaseURL(primary.URL, backup.URL+"/"), WithRetries(0),
+		WithEndpointProbeInterval(50*time.Millisecond))
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		if _, err := client.Email().Check(ctx, "user@gmail.com"); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	if p, b := atomic.LoadInt32(&primaryHits), atomic.LoadInt32(&backupHits); p != endpointFailures || b != 5 {
+		t.Errorf("hits = primary %d, backup %d; want %d and 5", p, b, endpointFailures)
+	}
+	st := client.Endpoints()
+	if len(st) != 2 || st[0].Healthy || st[0].ConsecutiveFailures != endpointFailures || st[0].LastError != "HTTP 502" || !st[1].Healthy {
+		t.Errorf("Endpoints() = %+v", st)
+	}
+
+	// Once the probe interval passes, one request probes the primary.
+	atomic.StoreInt32(&primaryDown, 0)
+	time.Sleep(60 * time.Millisecond)
+	if _, err := client.Email().Check(ctx, "user@gmail.com"); err != nil {
+		t.Fatal(err)
+	}
+	if p := atomic.LoadInt32(&primaryHits); p != endpointFailures+1 {
+		t.Errorf("primary hits = %d, want a probe", p)
+	}
+	if st := client.Endpoints(); !st[0].Healthy || st[0].ConsecutiveFailures != 0 {
+		t.Errorf("primary not healthy after a successful probe: %+v", st[0])
+	}
+}
+
+func TestFailover_NetworkErrorAndAllDown(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	backup := httptest.NewServer(http.HandlerFunc(emailHandler))
+	defer backup.Close()
+
+	client, _ := NewClient("sec4_test", WithBaseURL(dead.URL, backup.URL), WithRetries(0))
+	if _, err := client.Email().Check(context.Background(), "user@gmail.com"); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	client, _ = NewClient("sec4_test", WithBaseURL(dead.URL, down.URL), WithRetries(0))
+	_, err := client.Email().Check(context.Background(), "user@gmail.com")
+	if _, ok := err.(*ServerError); !ok {
+		t.Errorf("err = %T %v, want the last endpoint's ServerError", err, err)
+	}
+}
+
+func TestConfig_BaseURLList(t *testing.T) {
+	cfg := &Config{APIKey: "sec4_test", BaseURL: "https://gw.internal/api/v1/, https://api.sec4.dev/api/v1"}
+	client, err := NewClientFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := client.Endpoints()
+	if len(st) != 2 || st[0].URL != "https://gw.internal/api/v1" || st[1].URL != "https://api.sec4.dev/api/v1" {
+		t.Errorf("Endpoints() = %+v", st)
+	}
+}
@@ -0,0 +1,320 @@
+package sec4dev
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFileCacheMaxBytes = 64 << 20
+	minCompactRecords        = 1024
+)
+
+// FileCacheConfig configures a FileCache.
+type FileCacheConfig struct {
+	// MaxBytes caps the size of the cache file (default: 64 MiB). When it
+	// is exceeded the file is compacted, dropping the oldest entries.
+	MaxBytes int64
+	// MaxEntries, if positive, caps the number of entries the same way,
+	// compacting to the newest three quarters when it is exceeded.
+	MaxEntries int
+}
+
+// FileCache is a Cache stored in an append-only file, so results survive
+// restarts and are shared by processes on the same host using the same path.
+// Each record is checksummed; corrupt or truncated records are skipped. The
+// file is compacted when it exceeds MaxBytes or holds mostly stale records.
+// On Unix, processes coordinate through an advisory lock on path+".lock".
+type FileCache struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	maxItems int
+	lock     *os.File
+	file     *os.File // handle the index was read from, to detect replacement
+	offset   int64    // bytes of file read into entries
+	records  int      // records in file, including superseded ones
+	entries  map[string]CacheEntry
+}
+
+type fileRecord struct {
+	Key       string `json:"k"`
+	Value     []byte `json:"v"`
+	StoredAt  int64  `json:"s"`
+	ExpiresAt int64  `json:"e,omitempty"`
+}
+
+// NewFileCache opens or creates the cache file at path.
+func NewFileCache(path string, cfg FileCacheConfig) (*FileCache, error) {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = defaultFileCacheMaxBytes
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	lock, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	fc := &FileCache{path: path, maxBytes: cfg.MaxBytes, maxItems: cfg.MaxEntries, lock: lock}
+	if err := fc.withLock(false, fc.refresh); err != nil {
+		lock.Close()
+		return nil, err
+	}
+	return fc, nil
+}
+
+// Get returns the entry for key if present and not expired, picking up
+// records written by other processes since the last call.
+func (fc *FileCache) Get(key string) (CacheEntry, bool) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.withLock(false, fc.refresh)
+	entry, ok := fc.entries[key]
+	if !ok || expired(entry, time.Now()) {
+		return CacheEntry{}, false
+	}
+	return entry, true
+}
+
+// Set appends entry to the file. Write errors are ignored; the entry is
+// still kept in memory.
+func (fc *FileCache) Set(key string, entry CacheEntry) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.withLock(true, func() error {
+		if err := fc.refresh(); err != nil {
+			return err
+		}
+		if err := fc.append(key, entry); err != nil {
+			return err
+		}
+		if fc.offset > fc.maxBytes || fc.maxItems > 0 && len(fc.entries) > fc.maxItems ||
+			fc.records > minCompactRecords && fc.records > 2*len(fc.entries) {
+			return fc.compact()
+		}
+		return nil
+	})
+	fc.entries[key] = entry
+}
+
+// Compact rewrites the file with only live entries, newest first within
+// MaxBytes and MaxEntries.
+func (fc *FileCache) Compact() error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.withLock(true, func() error {
+		if err := fc.refresh(); err != nil {
+			return err
+		}
+		return fc.compact()
+	})
+}
+
+// Len returns the number of entries in memory, including expired ones not
+// yet compacted away.
+func (fc *FileCache) Len() int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return len(fc.entries)
+}
+
+// Close releases the cache's files.
+func (fc *FileCache) Close() error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if fc.file != nil {
+		fc.file.Close()
+		fc.file = nil
+	}
+	return fc.lock.Close()
+}
+
+func (fc *FileCache) withLock(exclusive bool, fn func() error) error {
+	if err := lockFile(fc.lock, exclusive); err != nil {
+		return err
+	}
+	defer unlockFile(fc.lock)
+	return fn()
+}
+
+// refresh brings entries up to date with the file, reloading it when another
+// process replaced it by compaction.
+func (fc *FileCache) refresh() error {
+	st, err := os.Stat(fc.path)
+	if os.IsNotExist(err) {
+		fc.reset(nil)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fc.file != nil {
+		cur, err := fc.file.Stat()
+		if err != nil || !os.SameFile(cur, st) || st.Size() < fc.offset {
+			fc.reset(nil)
+		}
+	}
+	if fc.file == nil {
+		f, err := os.Open(fc.path)
+		if err != nil {
+			return err
+		}
+		fc.reset(f)
+	}
+	if st.Size() == fc.offset {
+		return nil
+	}
+	if _, err := fc.file.Seek(fc.offset, io.SeekStart); err != nil {
+		return err
+	}
+	r := bufio.NewReader(fc.file)
+	now := time.Now()
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			// A trailing partial line is left for the next refresh, in case
+			// its writer is not done.
+			return nil
+		}
+		fc.offset += int64(len(line))
+		fc.records++
+		key, entry, ok := decodeFileRecord(line)
+		if !ok {
+			continue
+		}
+		if expired(entry, now) {
+			delete(fc.entries, key)
+		} else {
+			fc.entries[key] = entry
+		}
+	}
+}
+
+func (fc *FileCache) reset(f *os.File) {
+	if fc.file != nil {
+		fc.file.Close()
+	}
+	fc.file, fc.offset, fc.records = f, 0, 0
+	fc.entries = make(map[string]CacheEntry)
+}
+
+func (fc *FileCache) append(key string, entry CacheEntry) error {
+	f, err := os.OpenFile(fc.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	rec := encodeFileRecord(key, entry)
+	// Terminate a truncated record left by a crashed writer so it does not
+	// swallow this one.
+	if st, err := f.Stat(); err == nil && st.Size() > fc.offset {
+		rec = append([]byte{'\n'}, rec...)
+	}
+	if _, err := f.Write(rec); err != nil {
+		return err
+	}
+	return fc.refresh()
+}
+
+// compact writes live entries to a temporary file and renames it over the
+// cache file. The caller holds the exclusive lock.
+func (fc *FileCache) compact() error {
+	now := time.Now()
+	type item struct {
+		rec []byte
+		at  time.Time
+	}
+	var items []item
+	for k, e := range fc.entries {
+		if !expired(e, now) {
+			items = append(items, item{encodeFileRecord(k, e), e.StoredAt})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].at.After(items[j].at) })
+
+	tmp, err := os.CreateTemp(filepath.Dir(fc.path), filepath.Base(fc.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	limit := fc.maxBytes * 3 / 4
+	maxItems := len(items)
+	if fc.maxItems > 0 && maxItems > fc.maxItems*3/4 {
+		maxItems = fc.maxItems * 3 / 4
+		if maxItems == 0 {
+			maxItems = 1
+		}
+	}
+	var size int64
+	kept := 0
+	for _, it := range items {
+		if kept == maxItems || size+int64(len(it.rec)) > limit {
+			continue
+		}
+		size += int64(len(it.rec))
+		kept++
+		w.Write(it.rec)
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), fc.path); err != nil {
+		return err
+	}
+	fc.reset(nil)
+	return fc.refresh()
+}
+
+// encodeFileRecord formats a record as a CRC-32 in hex, a space and JSON.
+func encodeFileRecord(key string, e CacheEntry) []byte {
+	rec := fileRecord{Key: key, Value: e.Value, StoredAt: e.StoredAt.UnixNano()}
+	if !e.ExpiresAt.IsZero() {
+		rec.ExpiresAt = e.ExpiresAt.UnixNano()
+	}
+	b, _ := json.Marshal(rec)
+	return []byte(fmt.Sprintf("%08x %s\n", crc32.ChecksumIEEE(b), b))
+}
+
+func decodeFileRecord(line []byte) (string, CacheEntry, bool) {
+	line = bytes.TrimSuffix(line, []byte{'\n'})
+	if len(line) < 10 || line[8] != ' ' {
+		return "", CacheEntry{}, false
+	}
+	sum, err := strconv.ParseUint(string(line[:8]), 16, 32)
+	if err != nil || uint32(sum) != crc32.ChecksumIEEE(line[9:]) {
+		return "", CacheEntry{}, false
+	}
+	var rec fileRecord
+	if err := json.Unmarshal(line[9:], &rec); err != nil {
+		return "", CacheEntry{}, false
+	}
+	e := CacheEntry{Value: rec.Value, StoredAt: time.Unix(0, rec.StoredAt)}
+	if rec.ExpiresAt != 0 {
+		e.ExpiresAt = time.Unix(0, rec.ExpiresAt)
+	}
+	return rec.Key, e, true
+}
+
+func expired(e CacheEntry, now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && now.After(e.ExpiresAt)
+}
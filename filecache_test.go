@@ -0,0 +1,164 @@
+package sec4dev
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func fileEntry(v string, ttl time.Duration) CacheEntry {
+	now := time.Now()
+	return CacheEntry{Value: []byte(v), StoredAt: now, ExpiresAt: now.Add(ttl)}
+}
+
+func TestFileCache_PersistsAndShares(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "results.log")
+	a, err := NewFileCache(path, FileCacheConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := NewFileCache(path, FileCacheConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	a.Set("k1", fileEntry(`{"x":1}`, time.Hour))
+	a.Set("gone", fileEntry(`{}`, -time.Second))
+	if e, ok := b.Get("k1"); !ok || string(e.Value) != `{"x":1}` {
+		t.Fatalf("other instance Get = %q, %v", e.Value, ok)
+	}
+	if _, ok := b.Get("gone"); ok {
+		t.Error("expired entry returned")
+	}
+
+	b.Set("k2", fileEntry(`{"x":2}`, time.Hour))
+	if err := b.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	a.Set("k3", fileEntry(`{"x":3}`, time.Hour))
+	for _, c := range []*FileCache{a, b} {
+		for _, k := range []string{"k1", "k2", "k3"} {
+			if _, ok := c.Get(k); !ok {
+				t.Errorf("after compaction, %s missing", k)
+			}
+		}
+	}
+
+	reopened, err := NewFileCache(path, FileCacheConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if reopened.Len() != 3 {
+		t.Errorf("reopened Len = %d, want 3", reopened.Len())
+	}
+}
+
+func TestFileCache_ToleratesCorruption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.log")
+	good := encodeFileRecord("ok", fileEntry("v", time.Hour))
+	tampered := encodeFileRecord("bad", fileEntry("v", time.Hour))
+	tampered[len(tampered)-3] ^= 1
+	data := append(append(append([]byte("garbage line\n"), tampered...), good...), good[:len(good)/2]...)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewFileCache(path, FileCacheConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, ok := c.Get("ok"); !ok || c.Len() != 1 {
+		t.Fatalf("Len = %d, want only the intact record", c.Len())
+	}
+	c.Set("after", fileEntry("w", time.Hour))
+
+	reopened, err := NewFileCache(path, FileCacheConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if _, ok := reopened.Get("after"); !ok {
+		t.Error("record appended after a truncated one was lost")
+	}
+}
+
+func TestFileCache_CapsDiskUsage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.log")
+	c, err := NewFileCache(path, FileCacheConfig{MaxBytes: 4096})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	for i := 0; i < 200; i++ {
+		c.Set(fmt.Sprintf("key-%03d", i), fileEntry(`{"email":"user@example.com"}`, time.Hour))
+	}
+	st, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Size() > 4096 {
+		t.Errorf("file size = %d, want <= 4096", st.Size())
+	}
+	if _, ok := c.Get("key-199"); !ok {
+		t.Error("newest entry evicted")
+	}
+	if _, ok := c.Get("key-000"); ok {
+		t.Error("oldest entry kept past the cap")
+	}
+}
+
+func TestFileCache_CapsEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.log")
+	c, err := NewFileCache(path, FileCacheConfig{MaxEntries: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	for i := 0; i < 50; i++ {
+		c.Set(fmt.Sprintf("key-%03d", i), fileEntry(`{"email":"user@example.com"}`, time.Hour))
+		if n := c.Len(); n > 10 {
+			t.Fatalf("Len = %d after %d sets, want <= 10", n, i+1)
+		}
+	}
+	if _, ok := c.Get("key-049"); !ok {
+		t.Error("newest entry evicted")
+	}
+	if _, ok := c.Get("key-000"); ok {
+		t.Error("oldest entry kept past the cap")
+	}
+}
+
+func TestFileCache_SurvivesClientRestart(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		emailHandler(w, r)
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "results.log")
+
+	for i := 0; i < 2; i++ {
+		fc, err := NewFileCache(path, FileCacheConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithCache(fc, time.Hour))
+		if _, err := client.Email().Check(context.Background(), "user@gmail.com"); err != nil {
+			t.Fatal(err)
+		}
+		fc.Close()
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("API calls = %d, want 1", n)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package sec4dev
+
+import "os"
+
+// Without flock, FileCache is only safe within one process.
+
+func lockFile(f *os.File, exclusive bool) error { return nil }
+
+func unlockFile(f *os.File) error { return nil }
@@ -0,0 +1,193 @@
+package sec4dev
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	hedgeSamples    = 256
+	hedgeMinSamples = 20
+	hedgeMaxTokens  = 10
+)
+
+// HedgeConfig configures hedged requests. Zero fields take their defaults.
+type HedgeConfig struct {
+	// Percentile of recent attempt latencies after which a hedge is sent
+	// (default: 0.95).
+	Percentile float64
+	// MinDelay is the least time to wait before hedging (default: 10ms).
+	// Until enough latencies are observed, MinDelay is the delay.
+	MinDelay time.Duration
+	// Budget is the fraction of attempts that may be hedged (default: 0.05).
+	Budget float64
+}
+
+// WithHedging sends a second, identical attempt when the first has not
+// answered within a delay derived from recent latencies, keeps the first
+// successful response and cancels the other. Hedges are limited to a share
+// of attempts by the budget and to the request rate limit; they are safe
+// because checks are read-only.
+func WithHedging(cfg HedgeConfig) ClientOption {
+	return func(c *Client) {
+		if cfg.Percentile <= 0 || cfg.Percentile >= 1 {
+			cfg.Percentile = 0.95
+		}
+		if cfg.MinDelay <= 0 {
+			cfg.MinDelay = 10 * time.Millisecond
+		}
+		if cfg.Budget <= 0 {
+			cfg.Budget = 0.05
+		}
+		c.hedger = &hedger{cfg: cfg, latencies: make(map[string]*latencyWindow)}
+	}
+}
+
+type hedger struct {
+	mu        sync.Mutex
+	cfg       HedgeConfig
+	latencies map[string]*latencyWindow
+	tokens    float64
+}
+
+// latencyWindow is a ring of recent latencies.
+type latencyWindow struct {
+	samples []time.Duration
+	next    int
+}
+
+func (h *hedger) observe(path string, d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	w := h.latencies[path]
+	if w == nil {
+		w = &latencyWindow{}
+		h.latencies[path] = w
+	}
+	if len(w.samples) < hedgeSamples {
+		w.samples = append(w.samples, d)
+		return
+	}
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % hedgeSamples
+}
+
+// delay returns how long to wait before hedging an attempt on path, and
+// adds this attempt's share to the budget.
+func (h *hedger) delay(path string) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tokens += h.cfg.Budget
+	if h.tokens > hedgeMaxTokens {
+		h.tokens = hedgeMaxTokens
+	}
+	w := h.latencies[path]
+	if w == nil || len(w.samples) < hedgeMinSamples {
+		return h.cfg.MinDelay
+	}
+	sorted := append([]time.Duration(nil), w.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	d := sorted[int(h.cfg.Percentile*float64(len(sorted)-1))]
+	if d < h.cfg.MinDelay {
+		d = h.cfg.MinDelay
+	}
+	return d
+}
+
+// spend takes one hedge from the budget.
+func (h *hedger) spend() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.tokens < 1 {
+		return false
+	}
+	h.tokens--
+	return true
+}
+
+type attemptResult struct {
+	status int
+	out    []byte
+	header http.Header
+	err    error
+}
+
+// ok reports whether the attempt got an answer worth keeping.
+func (a attemptResult) ok() bool {
+	return a.err == nil && !isRetryable(a.status, false)
+}
+
+// attempt performs one HTTP attempt within the bulkhead, hedging it when
+// configured, and records its metrics.
+func (c *Client) attempt(ctx context.Context, r *Request, apiKey string) (int, []byte, http.Header, error) {
+	release, err := c.bulkhead.acquire(ctx)
+	if err != nil {
+		if _, ok := err.(*OverloadedError); ok {
+			c.metrics.observeOverloaded(r.Path)
+		}
+		return 0, nil, nil, err
+	}
+	if c.hedger == nil {
+		defer release()
+		start := time.Now()
+		status, out, header, err := c.do(ctx, r, apiKey)
+		c.metrics.observeAttempt(r.Path, status, time.Since(start))
+		return status, out, header, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan attemptResult, 2)
+	launch := func(release func()) {
+		go func() {
+			defer release()
+			start := time.Now()
+			status, out, header, err := c.do(ctx, r, apiKey)
+			if ctx.Err() == nil {
+				elapsed := time.Since(start)
+				c.metrics.observeAttempt(r.Path, status, elapsed)
+				if err == nil {
+					c.hedger.observe(r.Path, elapsed)
+				}
+			}
+			results <- attemptResult{status, out, header, err}
+		}()
+	}
+	launch(release)
+	timer := time.NewTimer(c.hedger.delay(r.Path))
+	defer timer.Stop()
+
+	pending := 1
+	var first *attemptResult
+	for {
+		select {
+		case <-timer.C:
+			if first != nil {
+				break
+			}
+			release, ok := c.bulkhead.tryAcquire()
+			if !ok {
+				break
+			}
+			if !c.hedger.spend() || !c.limiter.allow() {
+				release()
+				break
+			}
+			c.metrics.observeHedge(r.Path)
+			launch(release)
+			pending++
+		case res := <-results:
+			pending--
+			if res.ok() || pending == 0 {
+				if !res.ok() && first != nil {
+					res = *first
+				}
+				return res.status, res.out, res.header, res.err
+			}
+			first = &res
+		}
+	}
+}
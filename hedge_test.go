@@ -0,0 +1,81 @@
+package sec4dev
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowFirstServer delays its first response by slow and answers the rest
+// immediately.
+func slowFirstServer(slow time.Duration, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body) // lets the server notice a cancelled client
+		if atomic.AddInt32(calls, 1) == 1 {
+			select {
+			case <-time.After(slow):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		emailHandler(w, r)
+	}))
+}
+
+func TestHedging_FirstResponseWins(t *testing.T) {
+	var calls int32
+	server := slowFirstServer(2*time.Second, &calls)
+	defer server.Close()
+	m := NewMetrics()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithMetrics(m),
+		WithHedging(HedgeConfig{MinDelay: 20 * time.Millisecond, Budget: 1}))
+
+	start := time.Now()
+	if _, err := client.Email().Check(context.Background(), "user@gmail.com"); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Check took %v, hedge did not win", d)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("API calls = %d, want 2", n)
+	}
+	var sb strings.Builder
+	m.WriteTo(&sb)
+	if !strings.Contains(sb.String(), `sec4dev_hedged_requests_total{endpoint="/email/check"} 1`) {
+		t.Errorf("hedge not counted:\n%s", sb.String())
+	}
+}
+
+func TestHedging_RespectsBudget(t *testing.T) {
+	var calls int32
+	server := slowFirstServer(150*time.Millisecond, &calls)
+	defer server.Close()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL),
+		WithHedging(HedgeConfig{MinDelay: 10 * time.Millisecond, Budget: 0.01}))
+
+	if _, err := client.Email().Check(context.Background(), "user@gmail.com"); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("API calls = %d, want no hedge without budget", n)
+	}
+}
+
+func TestHedger_PercentileDelay(t *testing.T) {
+	h := &hedger{cfg: HedgeConfig{Percentile: 0.9, MinDelay: time.Millisecond, Budget: 0.05}, latencies: make(map[string]*latencyWindow)}
+	if d := h.delay("/ip/check"); d != time.Millisecond {
+		t.Errorf("delay without samples = %v, want MinDelay", d)
+	}
+	for i := 1; i <= 100; i++ {
+		h.observe("/ip/check", time.Duration(i)*time.Millisecond)
+	}
+	if d := h.delay("/ip/check"); d < 89*time.Millisecond || d > 91*time.Millisecond {
+		t.Errorf("p90 delay = %v", d)
+	}
+}
@@ -4,8 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
-	"math/rand"
 	"net/http"
 	"strconv"
 	"time"
@@ -39,36 +39,81 @@ func parseRateLimit(h http.Header) rateLimitHeaders {
 	}
 }
 
-func (c *Client) do(ctx context.Context, method, path string, body interface{}) (statusCode int, out []byte, header http.Header, err error) {
-	var reqBody io.Reader
-	if body != nil {
-		b, marshalErr := json.Marshal(body)
+// do sends r to the first base URL that answers without a network error or
+// 5xx, recording each URL's health along the way.
+func (c *Client) do(ctx context.Context, r *Request, apiKey string) (statusCode int, out []byte, header http.Header, err error) {
+	var body []byte
+	gzipped := false
+	if r.Body != nil {
+		b, marshalErr := json.Marshal(r.Body)
 		if marshalErr != nil {
 			return 0, nil, nil, marshalErr
 		}
-		reqBody = bytes.NewReader(b)
+		if body, gzipped, marshalErr = c.encodeRequestBody(b); marshalErr != nil {
+			return 0, nil, nil, marshalErr
+		}
+	}
+	for _, base := range c.endpointOrder() {
+		statusCode, out, header, err = c.doAt(ctx, base, r, body, gzipped, apiKey)
+		if ctx.Err() != nil {
+			return statusCode, out, header, err
+		}
+		switch {
+		case err != nil:
+			c.endpoints.report(base, err)
+		case statusCode >= 500:
+			c.endpoints.report(base, fmt.Errorf("HTTP %d", statusCode))
+		default:
+			c.endpoints.report(base, nil)
+			return statusCode, out, header, nil
+		}
+	}
+	return statusCode, out, header, err
+}
+
+// doAt makes one HTTP attempt against base, bounded by the attempt timeout.
+func (c *Client) doAt(ctx context.Context, base string, r *Request, body []byte, gzipped bool, apiKey string) (statusCode int, out []byte, header http.Header, err error) {
+	parent := ctx
+	if c.attemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.attemptTimeout)
+		defer cancel()
+	}
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
 	}
-	req, reqErr := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reqBody)
+	req, reqErr := http.NewRequestWithContext(ctx, r.Method, base+r.Path, reqBody)
 	if reqErr != nil {
 		return 0, nil, nil, reqErr
 	}
-	req.Header.Set("X-API-Key", c.APIKey)
+	req.Header.Set("X-API-Key", apiKey)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Encoding", "gzip")
+	if gzipped {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
 	req.Header.Set("User-Agent", "sec4dev-go/"+sdkVersion)
+	for k, v := range r.Header {
+		req.Header[k] = v
+	}
 
-	client := c.HTTPClient
-	if client == nil {
-		client = &http.Client{
-			Timeout: connectTimeout + readTimeout,
+	start := time.Now()
+	defer func() {
+		// Attempts abandoned by the caller, such as losing hedges, say
+		// nothing about the API.
+		if parent.Err() == nil {
+			rh := parseRateLimit(header)
+			c.adaptive.observe(r.Path, statusCode, time.Since(start), RateLimitInfo{Limit: rh.limit, Remaining: rh.remaining})
 		}
-	}
-	resp, doErr := client.Do(req)
+	}()
+	resp, doErr := c.httpClient().Do(req)
 	if doErr != nil {
 		return 0, nil, nil, doErr
 	}
 	defer resp.Body.Close()
-	out, readErr := io.ReadAll(resp.Body)
+	out, readErr := readResponseBody(resp)
 	if readErr != nil {
 		return resp.StatusCode, nil, nil, readErr
 	}
@@ -99,35 +144,59 @@ func parseErrorBody(body []byte) (message string, parsed interface{}) {
 	return "Unknown error", m
 }
 
-// postWithRetry performs POST with retries and returns response body and rate limit info.
-func (c *Client) postWithRetry(ctx context.Context, path string, body interface{}, onRateLimit func(RateLimitInfo)) ([]byte, RateLimitInfo, error) {
+// postWithRetry performs POST with retries and returns the final response.
+// The response is non-nil whenever the API answered, including on error.
+func (c *Client) postWithRetry(ctx context.Context, r *Request, apiKey string, onRateLimit func(RateLimitInfo)) (*Response, error) {
 	var lastErr error
 	var lastStatus int
 	var lastBody []byte
 	var lastHeader http.Header
 	rl := RateLimitInfo{}
+	if r.Method == "" {
+		r.Method = http.MethodPost
+	}
 
-	for attempt := 0; attempt <= c.Retries; attempt++ {
-		status, out, header, err := c.do(ctx, "POST", path, body)
+	retries := c.Retries
+	if r.noRetry {
+		retries = 0
+	}
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			c.metrics.observeRetry(r.Path)
+		}
+		if err := c.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+		status, out, header, err := c.attempt(ctx, r, apiKey)
 		if err != nil {
+			if _, ok := err.(*OverloadedError); ok {
+				return nil, err
+			}
 			lastErr = err
-			if attempt < c.Retries {
-				delay := time.Duration(c.RetryDelayMs)*time.Millisecond*time.Duration(1<<attempt) + time.Duration(rand.Intn(101))*time.Millisecond
-				select {
-				case <-ctx.Done():
-					return nil, rl, ctx.Err()
-				case <-time.After(delay):
+			if attempt < retries {
+				retry, sleepErr := c.sleepBeforeRetry(ctx, c.retryDelay(attempt))
+				if sleepErr != nil {
+					return nil, sleepErr
+				}
+				if retry {
 					continue
 				}
 			}
-			return nil, rl, err
+			return nil, err
 		}
 
 		rh := parseRateLimit(header)
 		rl = RateLimitInfo{Limit: rh.limit, Remaining: rh.remaining, ResetSeconds: rh.resetSeconds}
+		name := c.keyName(apiKey)
+		if header.Get("X-RateLimit-Remaining") != "" {
+			c.metrics.observeRateLimit(name, rl)
+			c.observeRateLimit(name, rl)
+		}
+		c.quota.observe(r.Path, name, rl)
 		if onRateLimit != nil {
 			onRateLimit(rl)
 		}
+		resp := &Response{StatusCode: status, Header: header, Body: out, RateLimit: rl}
 
 		if status == 429 {
 			retryAfter := 60
@@ -136,50 +205,79 @@ func (c *Client) postWithRetry(ctx context.Context, path string, body interface{
 					retryAfter = n
 				}
 			}
-			if attempt < c.Retries {
-				select {
-				case <-ctx.Done():
-					return nil, rl, ctx.Err()
-				case <-time.After(time.Duration(retryAfter) * time.Second):
+			// An exhausted pooled key is not worth waiting for while
+			// another can take over; fetch moves on instead.
+			exhausted := rh.limit > 0 && rh.remaining == 0 && c.canFailOver(apiKey)
+			if attempt < retries && !exhausted {
+				retry, sleepErr := c.sleepBeforeRetry(ctx, time.Duration(retryAfter)*time.Second)
+				if sleepErr != nil {
+					return resp, sleepErr
+				}
+				if retry {
 					continue
 				}
 			}
 			msg, parsed := parseErrorBody(out)
-			return nil, rl, errFromStatus(429, msg, parsed, retryAfter, rh.limit, rh.remaining)
+			return resp, errFromStatus(429, msg, parsed, retryAfter, rh.limit, rh.remaining)
 		}
 
 		if status >= 400 {
 			msg, parsed := parseErrorBody(out)
 			apiErr := errFromStatus(status, msg, parsed, 0, rh.limit, rh.remaining)
 			if !isRetryable(status, false) {
-				return nil, rl, apiErr
+				return resp, apiErr
 			}
 			lastErr = apiErr
 			lastStatus = status
 			lastBody = out
 			lastHeader = header
-			if attempt < c.Retries {
-				delay := time.Duration(c.RetryDelayMs)*time.Millisecond*time.Duration(1<<attempt) + time.Duration(rand.Intn(101))*time.Millisecond
-				select {
-				case <-ctx.Done():
-					return nil, rl, ctx.Err()
-				case <-time.After(delay):
+			if attempt < retries {
+				retry, sleepErr := c.sleepBeforeRetry(ctx, c.retryDelay(attempt))
+				if sleepErr != nil {
+					return resp, sleepErr
+				}
+				if retry {
 					continue
 				}
 			}
-			return nil, rl, apiErr
+			return resp, apiErr
 		}
 
-		return out, rl, nil
+		return resp, nil
 	}
 
 	if lastErr != nil && lastStatus >= 400 && lastHeader != nil {
 		msg, parsed := parseErrorBody(lastBody)
 		rh := parseRateLimit(lastHeader)
-		return nil, rl, errFromStatus(lastStatus, msg, parsed, 0, rh.limit, rh.remaining)
+		resp := &Response{StatusCode: lastStatus, Header: lastHeader, Body: lastBody, RateLimit: rl}
+		return resp, errFromStatus(lastStatus, msg, parsed, 0, rh.limit, rh.remaining)
 	}
 	if lastErr != nil {
-		return nil, rl, lastErr
+		return nil, lastErr
+	}
+	return nil, baseError("Request failed after retries", 0, nil)
+}
+
+// retryDelay is the exponential backoff, with jitter, before retry
+// attempt+1.
+func (c *Client) retryDelay(attempt int) time.Duration {
+	return time.Duration(c.RetryDelayMs)*time.Millisecond*time.Duration(1<<attempt) + time.Duration(c.jitter.Intn(101))*time.Millisecond
+}
+
+// sleepBeforeRetry waits d before a retry. It reports false without waiting
+// when ctx's deadline would pass first, so the caller returns the error in
+// hand instead of a bare deadline error, and returns ctx.Err() if ctx ends
+// while waiting.
+func (c *Client) sleepBeforeRetry(ctx context.Context, d time.Duration) (bool, error) {
+	if deadline, ok := ctx.Deadline(); ok && deadline.Sub(c.clock.Now()) < d {
+		return false, nil
+	}
+	timer := c.clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-timer.C():
+		return true, nil
 	}
-	return nil, rl, baseError("Request failed after retries", 0, nil)
 }
@@ -0,0 +1,155 @@
+package sec4dev
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Request is an API call as seen by interceptors. Header values are added to
+// every HTTP attempt and override the SDK defaults.
+type Request struct {
+	Method string
+	Path   string
+	Body   interface{}
+	Header http.Header
+
+	noCache bool
+	refresh bool // skip the cache lookup but store the result
+	noRetry bool
+}
+
+// Response is the outcome of an API call as seen by interceptors. Result holds
+// the decoded *EmailCheckResult or *IPCheckResult once the call succeeded.
+// For CheckBatch requests (Path "/email/check/batch" or "/ip/check/batch")
+// it holds the chunk's []EmailBatchResult or []IPBatchResult instead, one per
+// input in request order.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	RateLimit  RateLimitInfo
+	Result     interface{}
+}
+
+// Next invokes the rest of the interceptor chain.
+type Next func(ctx context.Context, req *Request) (*Response, error)
+
+// Interceptor wraps an API call. It may modify the request before calling
+// next, and inspect or replace the response and error it returns. Retries
+// happen inside next, so the error seen is the final one.
+type Interceptor func(ctx context.Context, req *Request, next Next) (*Response, error)
+
+// WithInterceptor appends interceptors to the chain. Interceptors run in the
+// order registered: the first one is outermost.
+func WithInterceptor(interceptors ...Interceptor) ClientOption {
+	return func(c *Client) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+// call runs req through the interceptor chain. The innermost handler performs
+// the request with retries and decodes the body with decode.
+func (c *Client) call(ctx context.Context, req *Request, decode func([]byte) (interface{}, error)) (*Response, error) {
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	next := func(ctx context.Context, req *Request) (*Response, error) {
+		return c.send(ctx, req, decode)
+	}
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		ic, inner := c.interceptors[i], next
+		next = func(ctx context.Context, req *Request) (*Response, error) {
+			return ic(ctx, req, inner)
+		}
+	}
+	return next(ctx, req)
+}
+
+func (c *Client) send(ctx context.Context, req *Request, decode func([]byte) (interface{}, error)) (*Response, error) {
+	var stale *Response
+	if c.cache != nil && !req.noCache && !req.refresh {
+		resp, age, ok := c.lookup(req, decode)
+		switch {
+		case ok && age <= c.cacheTTL:
+			c.metrics.ObserveCacheHit(req.Path)
+			return resp, nil
+		case ok && age <= c.cacheTTL+c.staleWhileRevalidate:
+			c.metrics.observeStale(req.Path)
+			c.revalidate(ctx, req, decode)
+			markStale(resp.Result, age)
+			return resp, nil
+		case ok && age <= c.cacheTTL+c.staleIfError:
+			markStale(resp.Result, age)
+			stale = resp
+			req.noRetry = true
+		}
+		c.metrics.ObserveCacheMiss(req.Path)
+	}
+	onRateLimit := func(r RateLimitInfo) {
+		if c.onRateLimit != nil {
+			c.onRateLimit(r)
+		}
+	}
+	resp, err := c.coalesce(ctx, req, func(ctx context.Context) (*Response, error) {
+		return c.fetch(ctx, req, onRateLimit)
+	})
+	if err != nil {
+		if stale != nil && canServeStale(ctx, err) {
+			c.metrics.observeStale(req.Path)
+			return stale, nil
+		}
+		return resp, err
+	}
+	result, err := decode(resp.Body)
+	if err != nil {
+		return resp, err
+	}
+	resp.Result = result
+	if !req.noCache {
+		c.store(req, resp.Body)
+	}
+	return resp, nil
+}
+
+// fetch performs req against the API, moving on to another key when the
+// credential provider offers one after a failure. The operation timeout
+// covers all keys tried.
+func (c *Client) fetch(ctx context.Context, req *Request, onRateLimit func(RateLimitInfo)) (*Response, error) {
+	if c.operationTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = c.withTimeout(ctx, c.operationTimeout)
+		defer cancel()
+	}
+	key, err := c.apiKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.postWithRetry(ctx, req, key, onRateLimit)
+	tried := map[string]bool{key: true}
+	for {
+		c.observeKey(key, resp, err)
+		c.quota.observeError(err)
+		next, ok := c.nextAPIKey(ctx, key, err)
+		if !ok || tried[next] {
+			break
+		}
+		tried[next] = true
+		key = next
+		resp, err = c.postWithRetry(ctx, req, key, onRateLimit)
+	}
+	return resp, err
+}
+
+// resultAs extracts the decoded result from an interceptor chain response.
+func resultAs[T any](resp *Response) (*T, error) {
+	if resp == nil {
+		return nil, baseError("No response from interceptor chain", 0, nil)
+	}
+	r, ok := resp.Result.(*T)
+	if !ok || r == nil {
+		var want *T
+		return nil, baseError(fmt.Sprintf("Unexpected result type %T, want %T", resp.Result, want), resp.StatusCode, nil)
+	}
+	return r, nil
+}
@@ -0,0 +1,114 @@
+package sec4dev
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInterceptor_OrderAndHeaders(t *testing.T) {
+	var gotHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("X-Trace-Id")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"email": "user@gmail.com", "domain": "gmail.com", "is_disposable": false,
+		})
+	}))
+	defer server.Close()
+
+	var order []string
+	outer := func(ctx context.Context, req *Request, next Next) (*Response, error) {
+		order = append(order, "outer-before")
+		req.Header.Set("X-Trace-Id", "abc123")
+		resp, err := next(ctx, req)
+		order = append(order, "outer-after")
+		return resp, err
+	}
+	inner := func(ctx context.Context, req *Request, next Next) (*Response, error) {
+		order = append(order, "inner-before")
+		resp, err := next(ctx, req)
+		if _, ok := resp.Result.(*EmailCheckResult); !ok {
+			t.Errorf("Result = %T, want *EmailCheckResult", resp.Result)
+		}
+		order = append(order, "inner-after")
+		return resp, err
+	}
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL+"/api/v1"), WithHTTPClient(server.Client()),
+		WithInterceptor(outer), WithInterceptor(inner))
+
+	if _, err := client.Email().Check(context.Background(), "user@gmail.com"); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if gotHeader != "abc123" {
+		t.Errorf("X-Trace-Id = %q", gotHeader)
+	}
+	want := []string{"outer-before", "inner-before", "inner-after", "outer-after"}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v", order)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestInterceptor_MutatesResult(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"email": "x@partner.com", "domain": "partner.com", "is_disposable": true,
+		})
+	}))
+	defer server.Close()
+
+	override := func(ctx context.Context, req *Request, next Next) (*Response, error) {
+		resp, err := next(ctx, req)
+		if err == nil {
+			resp.Result.(*EmailCheckResult).IsDisposable = false
+		}
+		return resp, err
+	}
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL+"/api/v1"), WithHTTPClient(server.Client()),
+		WithInterceptor(override))
+
+	ok, err := client.Email().IsDisposable(context.Background(), "x@partner.com")
+	if err != nil {
+		t.Fatalf("IsDisposable: %v", err)
+	}
+	if ok {
+		t.Error("expected interceptor to clear IsDisposable")
+	}
+}
+
+func TestInterceptor_SeesFinalError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"detail": "Invalid API key"})
+	}))
+	defer server.Close()
+
+	var seen error
+	var status int
+	audit := func(ctx context.Context, req *Request, next Next) (*Response, error) {
+		resp, err := next(ctx, req)
+		seen = err
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		return resp, err
+	}
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL+"/api/v1"), WithHTTPClient(server.Client()),
+		WithInterceptor(audit))
+
+	_, err := client.IP().Check(context.Background(), "203.0.113.42")
+	if _, ok := err.(*AuthenticationError); !ok {
+		t.Fatalf("expected AuthenticationError, got %T", err)
+	}
+	if seen != err || status != http.StatusUnauthorized {
+		t.Errorf("interceptor saw err=%v status=%d", seen, status)
+	}
+}
@@ -3,6 +3,8 @@ package sec4dev
 import (
 	"context"
 	"encoding/json"
+	"net/http"
+	"net/netip"
 	"strings"
 )
 
@@ -16,21 +18,53 @@ func (s *IPService) Check(ctx context.Context, ip string) (*IPCheckResult, error
 	if err := ValidateIP(ip); err != nil {
 		return nil, err
 	}
-	path := "/ip/check"
-	body := map[string]string{"ip": strings.TrimSpace(ip)}
-	var onRateLimit func(RateLimitInfo)
-	if s.client.onRateLimit != nil {
-		onRateLimit = func(r RateLimitInfo) {
-			s.client.rateLimit = r
-			s.client.onRateLimit(r)
-		}
-	} else {
-		onRateLimit = func(r RateLimitInfo) { s.client.rateLimit = r }
-	}
-	out, _, err := s.client.postWithRetry(ctx, path, body, onRateLimit)
+	if r, ok := s.local(ip); ok {
+		return r, nil
+	}
+	r, err := s.check(ctx, ip)
+	if err != nil {
+		return nil, err
+	}
+	s.client.prefixes.learn(r)
+	return r, nil
+}
+
+// local answers a validated IP from the overrides or the prefix cache.
+func (s *IPService) local(ip string) (*IPCheckResult, bool) {
+	if r, ok := s.client.overrides.ip(ip); ok {
+		return r, true
+	}
+	if s.client.prefixes == nil {
+		return nil, false
+	}
+	ip = strings.TrimSpace(ip)
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil, false
+	}
+	r, ok := s.client.prefixes.lookup(netip.PrefixFrom(addr, addr.BitLen()))
+	if ok {
+		s.client.metrics.ObserveCacheHit("/ip/check")
+		r.IP = ip
+	}
+	return r, ok
+}
+
+// check sends a validated IP to the API.
+func (s *IPService) check(ctx context.Context, ip string) (*IPCheckResult, error) {
+	req := &Request{
+		Method: http.MethodPost,
+		Path:   "/ip/check",
+		Body:   map[string]string{"ip": strings.TrimSpace(ip)},
+	}
+	resp, err := s.client.call(ctx, req, decodeIPCheckResult)
 	if err != nil {
 		return nil, err
 	}
+	return resultAs[IPCheckResult](resp)
+}
+
+func decodeIPCheckResult(out []byte) (interface{}, error) {
 	var raw struct {
 		IP             string  `json:"ip"`
 		Classification string  `json:"classification"`
@@ -47,6 +81,7 @@ func (s *IPService) Check(ctx context.Context, ip string) (*IPCheckResult, error
 			ASN      *int   `json:"asn"`
 			Org      string `json:"org"`
 			Provider string `json:"provider"`
+			Prefix   string `json:"prefix"`
 		} `json:"network"`
 		Geo struct {
 			Country string `json:"country"`
@@ -72,6 +107,7 @@ func (s *IPService) Check(ctx context.Context, ip string) (*IPCheckResult, error
 			ASN:      raw.Network.ASN,
 			Org:      raw.Network.Org,
 			Provider: raw.Network.Provider,
+			Prefix:   raw.Network.Prefix,
 		},
 		Geo: IPGeo{
 			Country: raw.Geo.Country,
@@ -0,0 +1,226 @@
+package sec4dev
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultKeyCooldown = 5 * time.Minute
+
+// CredentialObserver is implemented by providers that track the outcome of
+// each call per key, such as KeyPool. err is nil on success.
+type CredentialObserver interface {
+	ObserveKey(key string, rl RateLimitInfo, err error)
+}
+
+// PoolKey is one API key in a KeyPool.
+type PoolKey struct {
+	Key string
+	// Name labels the key in Status, metrics and quota tracking, and must be
+	// unique in the pool. Defaults to the key's index and its last four
+	// characters, e.g. "key1-3f9a".
+	Name string
+	// Weight is the key's relative share of traffic. Defaults to 1.
+	Weight int
+}
+
+// KeyPoolConfig configures a KeyPool.
+type KeyPoolConfig struct {
+	Keys []PoolKey
+	// Cooldown is how long a key is skipped after 402 or 403 when the
+	// response gives no reset time. Defaults to 5 minutes.
+	Cooldown time.Duration
+}
+
+// KeyStatus describes one pooled key.
+type KeyStatus struct {
+	Name      string
+	Weight    int
+	RateLimit RateLimitInfo
+	Available bool
+	// DisabledUntil is when a skipped key becomes eligible again.
+	DisabledUntil time.Time
+	// LastError is the error that disabled the key.
+	LastError error
+}
+
+// KeyPool spreads calls across several API keys by weight. A key that
+// returns PaymentRequiredError, ForbiddenError or AuthenticationError, or
+// reports Remaining == 0, is skipped until its window resets or the cooldown
+// ends, and the failed call is retried once on another key. A 429 with
+// Remaining == 0 moves to another available key instead of waiting out
+// Retry-After. Use it with
+// NewClientWithCredentials or WithCredentials.
+type KeyPool struct {
+	mu       sync.Mutex
+	keys     []*pooledKey
+	cooldown time.Duration
+	rng      *rand.Rand
+}
+
+type pooledKey struct {
+	PoolKey
+	rateLimit     RateLimitInfo
+	disabledUntil time.Time
+	lastErr       error
+}
+
+// NewKeyPool creates a key pool.
+func NewKeyPool(cfg KeyPoolConfig) (*KeyPool, error) {
+	if len(cfg.Keys) == 0 {
+		return nil, &ValidationError{baseError("Key pool needs at least one key", 422, nil)}
+	}
+	p := &KeyPool{
+		cooldown: cfg.Cooldown,
+		rng:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	if p.cooldown <= 0 {
+		p.cooldown = defaultKeyCooldown
+	}
+	names := make(map[string]bool, len(cfg.Keys))
+	for i, k := range cfg.Keys {
+		k.Key = strings.TrimSpace(k.Key)
+		if !strings.HasPrefix(k.Key, "sec4_") {
+			return nil, &ValidationError{baseError("API key must start with sec4_", 422, nil)}
+		}
+		if k.Weight <= 0 {
+			k.Weight = 1
+		}
+		if k.Name == "" {
+			k.Name = defaultKeyName(i, k.Key)
+		}
+		if names[k.Name] {
+			return nil, &ValidationError{baseError(fmt.Sprintf("Duplicate key pool name %q", k.Name), 422, nil)}
+		}
+		names[k.Name] = true
+		p.keys = append(p.keys, &pooledKey{PoolKey: k})
+	}
+	return p, nil
+}
+
+// APIKey picks an available key at random by weight. If every key is
+// disabled it returns the one that becomes available first.
+func (p *KeyPool) APIKey(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	total := 0
+	for _, k := range p.keys {
+		if !now.Before(k.disabledUntil) {
+			total += k.Weight
+		}
+	}
+	if total == 0 {
+		soonest := p.keys[0]
+		for _, k := range p.keys[1:] {
+			if k.disabledUntil.Before(soonest.disabledUntil) {
+				soonest = k
+			}
+		}
+		return soonest.Key, nil
+	}
+	n := p.rng.Intn(total)
+	for _, k := range p.keys {
+		if now.Before(k.disabledUntil) {
+			continue
+		}
+		if n < k.Weight {
+			return k.Key, nil
+		}
+		n -= k.Weight
+	}
+	return p.keys[len(p.keys)-1].Key, nil
+}
+
+// ObserveKey records the rate limit headers and outcome of a call made with key.
+func (p *KeyPool) ObserveKey(key string, rl RateLimitInfo, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var k *pooledKey
+	for _, pk := range p.keys {
+		if pk.Key == key {
+			k = pk
+			break
+		}
+	}
+	if k == nil {
+		return
+	}
+	if rl.Limit > 0 {
+		k.rateLimit = rl
+	}
+	now := time.Now()
+	reset := p.cooldown
+	if rl.ResetSeconds > 0 {
+		reset = time.Duration(rl.ResetSeconds) * time.Second
+	}
+	switch err.(type) {
+	case *PaymentRequiredError, *ForbiddenError, *AuthenticationError:
+		k.disabledUntil = now.Add(reset)
+		k.lastErr = err
+		return
+	}
+	if rl.Limit > 0 && rl.Remaining == 0 {
+		k.disabledUntil = now.Add(reset)
+		k.lastErr = nil
+		return
+	}
+	if err == nil {
+		k.disabledUntil = time.Time{}
+		k.lastErr = nil
+	}
+}
+
+// Status returns the state of every key in the pool.
+func (p *KeyPool) Status() []KeyStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	out := make([]KeyStatus, len(p.keys))
+	for i, k := range p.keys {
+		out[i] = KeyStatus{
+			Name:          k.Name,
+			Weight:        k.Weight,
+			RateLimit:     k.rateLimit,
+			Available:     !now.Before(k.disabledUntil),
+			DisabledUntil: k.disabledUntil,
+			LastError:     k.lastErr,
+		}
+	}
+	return out
+}
+
+// availableExcept reports whether a key other than key is available.
+func (p *KeyPool) availableExcept(key string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	for _, k := range p.keys {
+		if k.Key != key && !now.Before(k.disabledUntil) {
+			return true
+		}
+	}
+	return false
+}
+
+// name returns the Name of key, or "" if it is not in the pool.
+func (p *KeyPool) name(key string) string {
+	for _, k := range p.keys {
+		if k.Key == key {
+			return k.Name
+		}
+	}
+	return ""
+}
+
+// defaultKeyName names the key at index i without revealing it.
+func defaultKeyName(i int, key string) string {
+	if len(key) > 4 {
+		key = key[len(key)-4:]
+	}
+	return fmt.Sprintf("key%d-%s", i, key)
+}
@@ -0,0 +1,160 @@
+package sec4dev
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestKeyPool_FailsOverOnPaymentRequired(t *testing.T) {
+	counts := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("X-API-Key")
+		counts[key]++
+		w.Header().Set("X-RateLimit-Limit", "100")
+		w.Header().Set("X-RateLimit-Remaining", "50")
+		if key == "sec4_primary" {
+			w.WriteHeader(http.StatusPaymentRequired)
+			json.NewEncoder(w).Encode(map[string]string{"detail": "Quota exceeded"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"email": "user@gmail.com", "domain": "gmail.com", "is_disposable": false,
+		})
+	}))
+	defer server.Close()
+
+	pool, err := NewKeyPool(KeyPoolConfig{Keys: []PoolKey{
+		{Key: "sec4_primary", Name: "primary", Weight: 1000},
+		{Key: "sec4_burst", Name: "burst", Weight: 1},
+	}})
+	if err != nil {
+		t.Fatalf("NewKeyPool: %v", err)
+	}
+	client, _ := NewClientWithCredentials(pool, WithBaseURL(server.URL+"/api/v1"), WithHTTPClient(server.Client()))
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		if _, err := client.Email().Check(ctx, "user@gmail.com"); err != nil {
+			t.Fatalf("Check %d: %v", i, err)
+		}
+	}
+	if counts["sec4_primary"] > 1 || counts["sec4_burst"] != 5 {
+		t.Errorf("counts = %v", counts)
+	}
+	st := pool.Status()
+	if st[0].Available || st[0].LastError == nil {
+		t.Errorf("primary status = %+v", st[0])
+	}
+	if !st[1].Available || st[1].RateLimit.Remaining != 50 {
+		t.Errorf("burst status = %+v", st[1])
+	}
+}
+
+func TestKeyPool_SkipsExhaustedKeys(t *testing.T) {
+	pool, _ := NewKeyPool(KeyPoolConfig{Keys: []PoolKey{{Key: "sec4_a"}, {Key: "sec4_b"}}})
+	pool.ObserveKey("sec4_a", RateLimitInfo{Limit: 10, Remaining: 0, ResetSeconds: 60}, nil)
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		if k, _ := pool.APIKey(ctx); k != "sec4_b" {
+			t.Fatalf("APIKey = %q, want sec4_b", k)
+		}
+	}
+	pool.ObserveKey("sec4_b", RateLimitInfo{}, &ForbiddenError{baseError("Account deactivated", 403, nil)})
+	if k, _ := pool.APIKey(ctx); k != "sec4_a" {
+		t.Errorf("with all keys disabled, APIKey = %q, want the one available soonest", k)
+	}
+}
+
+func TestKeyPool_WeightsTraffic(t *testing.T) {
+	pool, _ := NewKeyPool(KeyPoolConfig{Keys: []PoolKey{{Key: "sec4_a", Weight: 3}, {Key: "sec4_b", Weight: 1}}})
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		k, _ := pool.APIKey(context.Background())
+		counts[k]++
+	}
+	if counts["sec4_a"] < 2700 || counts["sec4_a"] > 3300 {
+		t.Errorf("counts = %v, want about 3:1", counts)
+	}
+}
+
+func TestNewKeyPool_RejectsInvalid(t *testing.T) {
+	if _, err := NewKeyPool(KeyPoolConfig{}); err == nil {
+		t.Error("expected error for empty pool")
+	}
+	if _, err := NewKeyPool(KeyPoolConfig{Keys: []PoolKey{{Key: "bad"}}}); err == nil {
+		t.Error("expected error for invalid key")
+	}
+	_, err := NewKeyPool(KeyPoolConfig{Keys: []PoolKey{{Key: "sec4_a", Name: "main"}, {Key: "sec4_b", Name: "main"}}})
+	if _, ok := err.(*ValidationError); !ok {
+		t.Errorf("duplicate names: err = %v, want ValidationError", err)
+	}
+}
+
+func TestNewKeyPool_DefaultNamesUnique(t *testing.T) {
+	pool, err := NewKeyPool(KeyPoolConfig{Keys: []PoolKey{{Key: "sec4_live_aaaaaaaa"}, {Key: "sec4_live_bbbbbbbb"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := pool.Status()
+	if st[0].Name != "key0-aaaa" || st[1].Name != "key1-bbbb" {
+		t.Errorf("names = %q, %q", st[0].Name, st[1].Name)
+	}
+}
+
+func poolServer(t *testing.T, exhausted string, status int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") == exhausted {
+			w.Header().Set("X-RateLimit-Limit", "100")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]string{"detail": "Quota exceeded"})
+			return
+		}
+		emailHandler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestKeyPool_PaymentRequiredCallbackOnFailover(t *testing.T) {
+	server := poolServer(t, "sec4_a", http.StatusPaymentRequired)
+	var seen []*PaymentRequiredError
+	tracker := NewQuotaTracker(QuotaConfig{OnPaymentRequired: func(e *PaymentRequiredError) { seen = append(seen, e) }})
+	pool, _ := NewKeyPool(KeyPoolConfig{Keys: []PoolKey{{Key: "sec4_a", Weight: 1000}, {Key: "sec4_b", Weight: 1}}})
+	client, _ := NewClientWithCredentials(pool, WithBaseURL(server.URL), WithQuotaTracker(tracker))
+
+	if _, err := client.Email().Check(context.Background(), "user@gmail.com"); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 1 {
+		t.Errorf("OnPaymentRequired calls = %d, want 1 for the failed-over key", len(seen))
+	}
+}
+
+func TestKeyPool_FailsOverOnExhaustedRateLimit(t *testing.T) {
+	server := poolServer(t, "sec4_a", http.StatusTooManyRequests)
+	pool, _ := NewKeyPool(KeyPoolConfig{Keys: []PoolKey{{Key: "sec4_a", Weight: 1000}, {Key: "sec4_b", Weight: 1}}})
+	client, _ := NewClientWithCredentials(pool, WithBaseURL(server.URL), WithRetries(3))
+
+	start := time.Now()
+	if _, err := client.Email().Check(context.Background(), "user@gmail.com"); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("took %v, want no Retry-After wait while another key is available", d)
+	}
+	if st := pool.Status(); st[0].Available {
+		t.Errorf("exhausted key still available: %+v", st[0])
+	}
+
+	// A lone key still waits out Retry-After rather than failing at once.
+	single, _ := NewKeyPool(KeyPoolConfig{Keys: []PoolKey{{Key: "sec4_a"}}})
+	if single.availableExcept("sec4_a") {
+		t.Error("availableExcept found another key in a one-key pool")
+	}
+}
@@ -0,0 +1,163 @@
+package sec4dev
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WithRequestRate limits the client to perSecond HTTP attempts per second,
+// allowing bursts of up to burst attempts. Retries count against the limit.
+func WithRequestRate(perSecond float64, burst int) ClientOption {
+	return func(c *Client) {
+		if perSecond <= 0 {
+			c.limiter = nil
+			return
+		}
+		c.limiter = newLimiter(perSecond, burst)
+	}
+}
+
+// limiter is a token bucket. Callers that find no token wait in a queue per
+// priority; each token goes to the oldest interactive waiter, then to the
+// oldest background one.
+type limiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+	waiters [numPriorities][]*limitWaiter
+	timer   *time.Timer
+}
+
+type limitWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+func newLimiter(rate float64, burst int) *limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &limiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// refill adds the tokens accrued since the last call. The caller holds l.mu.
+func (l *limiter) refill() {
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+}
+
+// queued returns the number of waiters at priority p or higher. The caller
+// holds l.mu.
+func (l *limiter) queued(p Priority) int {
+	n := 0
+	for q := PriorityInteractive; q <= p; q++ {
+		n += len(l.waiters[q])
+	}
+	return n
+}
+
+// allow takes a token if one is available now and nobody is waiting.
+func (l *limiter) allow() bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	if l.tokens < 1 || l.queued(PriorityBackground) > 0 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// wait blocks until a token is available or ctx is done. Calls wait behind
+// those of the same or a higher priority only.
+func (l *limiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	p := PriorityFrom(ctx)
+	l.mu.Lock()
+	l.refill()
+	if l.tokens >= 1 && l.queued(p) == 0 {
+		l.tokens--
+		l.mu.Unlock()
+		return nil
+	}
+	w := &limitWaiter{ready: make(chan struct{})}
+	l.waiters[p] = append(l.waiters[p], w)
+	l.schedule()
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		if w.granted {
+			// The token arrived as ctx ended; pass it on.
+			l.tokens++
+			l.dispatch()
+		} else {
+			l.remove(p, w)
+		}
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// dispatch hands available tokens to waiters in priority order and arms the
+// timer for the next one. The caller holds l.mu.
+func (l *limiter) dispatch() {
+	l.refill()
+	for l.tokens >= 1 {
+		var w *limitWaiter
+		for p := range l.waiters {
+			if len(l.waiters[p]) > 0 {
+				w = l.waiters[p][0]
+				l.waiters[p] = l.waiters[p][1:]
+				break
+			}
+		}
+		if w == nil {
+			return
+		}
+		l.tokens--
+		w.granted = true
+		close(w.ready)
+	}
+	l.schedule()
+}
+
+// schedule arms the timer for when the next token accrues, if anyone is
+// waiting. The caller holds l.mu.
+func (l *limiter) schedule() {
+	if l.queued(PriorityBackground) == 0 || l.timer != nil {
+		return
+	}
+	d := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	l.timer = time.AfterFunc(d, func() {
+		l.mu.Lock()
+		l.timer = nil
+		l.dispatch()
+		l.mu.Unlock()
+	})
+}
+
+// remove drops a waiter that gave up. The caller holds l.mu.
+func (l *limiter) remove(p Priority, w *limitWaiter) {
+	for i, x := range l.waiters[p] {
+		if x == w {
+			l.waiters[p] = append(l.waiters[p][:i], l.waiters[p][i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package sec4dev
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiter_SpacesRequests(t *testing.T) {
+	l := newLimiter(50, 1)
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.wait(ctx); err != nil {
+			t.Fatalf("wait: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("3 waits at 50/s took %v, want >= 35ms (40ms less timer slack)", elapsed)
+	}
+}
+
+func TestLimiter_ContextCancelled(t *testing.T) {
+	l := newLimiter(1, 1)
+	l.wait(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("wait = %v, want DeadlineExceeded", err)
+	}
+}
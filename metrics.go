@@ -0,0 +1,314 @@
+package sec4dev
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are the request duration histogram buckets in seconds.
+var DefaultLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Metrics collects client request metrics and renders them in the Prometheus
+// text exposition format. It implements http.Handler so it can be mounted
+// directly on a scrape endpoint. A nil *Metrics records nothing.
+type Metrics struct {
+	mu          sync.Mutex
+	buckets     []float64
+	requests    map[[2]string]uint64
+	latency     map[string]*histogram
+	retries     map[string]uint64
+	rateLimited map[string]uint64
+	cacheHits   map[string]uint64
+	cacheMisses map[string]uint64
+	cacheStale  map[string]uint64
+	hedges      map[string]uint64
+	overloaded  map[string]uint64
+	coalesced   map[string]uint64
+	quota       map[string]RateLimitInfo // by KeyPool key name
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewMetrics creates a metrics collector. If no buckets are given,
+// DefaultLatencyBuckets is used.
+func NewMetrics(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	return &Metrics{
+		buckets:     b,
+		requests:    make(map[[2]string]uint64),
+		latency:     make(map[string]*histogram),
+		retries:     make(map[string]uint64),
+		rateLimited: make(map[string]uint64),
+		cacheHits:   make(map[string]uint64),
+		cacheMisses: make(map[string]uint64),
+		cacheStale:  make(map[string]uint64),
+		hedges:      make(map[string]uint64),
+		overloaded:  make(map[string]uint64),
+		coalesced:   make(map[string]uint64),
+		quota:       make(map[string]RateLimitInfo),
+	}
+}
+
+// WithMetrics records request metrics into m.
+func WithMetrics(m *Metrics) ClientOption {
+	return func(c *Client) {
+		c.metrics = m
+	}
+}
+
+// ObserveCacheHit counts a result served from a cache for endpoint.
+func (m *Metrics) ObserveCacheHit(endpoint string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.cacheHits[endpoint]++
+	m.mu.Unlock()
+}
+
+// ObserveCacheMiss counts a cache lookup for endpoint that had to call the API.
+func (m *Metrics) ObserveCacheMiss(endpoint string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.cacheMisses[endpoint]++
+	m.mu.Unlock()
+}
+
+// observeStale counts a stale cached result served for endpoint.
+func (m *Metrics) observeStale(endpoint string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.cacheStale[endpoint]++
+	m.mu.Unlock()
+}
+
+// observeHedge counts a hedged attempt sent for endpoint.
+func (m *Metrics) observeHedge(endpoint string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.hedges[endpoint]++
+	m.mu.Unlock()
+}
+
+// observeOverloaded counts a call for endpoint shed by the bulkhead.
+func (m *Metrics) observeOverloaded(endpoint string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.overloaded[endpoint]++
+	m.mu.Unlock()
+}
+
+// observeCoalesced counts a call for endpoint that shared another call's
+// request.
+func (m *Metrics) observeCoalesced(endpoint string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.coalesced[endpoint]++
+	m.mu.Unlock()
+}
+
+// observeAttempt records one HTTP attempt. status is 0 for network errors.
+func (m *Metrics) observeAttempt(endpoint string, status int, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	code := "error"
+	if status > 0 {
+		code = strconv.Itoa(status)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[[2]string{endpoint, code}]++
+	if status == 429 {
+		m.rateLimited[endpoint]++
+	}
+	h := m.latency[endpoint]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.latency[endpoint] = h
+	}
+	secs := elapsed.Seconds()
+	for i, b := range m.buckets {
+		if secs <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += secs
+	h.count++
+}
+
+func (m *Metrics) observeRetry(endpoint string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.retries[endpoint]++
+	m.mu.Unlock()
+}
+
+func (m *Metrics) observeRateLimit(key string, rl RateLimitInfo) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.quota[key] = rl
+	m.mu.Unlock()
+}
+
+// ServeHTTP writes the metrics in Prometheus text format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in Prometheus text format to w.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	if m != nil {
+		m.mu.Lock()
+		m.write(cw)
+		m.mu.Unlock()
+	}
+	if err := cw.w.Flush(); err != nil && cw.err == nil {
+		cw.err = err
+	}
+	return cw.n, cw.err
+}
+
+func (m *Metrics) write(w *countingWriter) {
+	w.header("sec4dev_requests_total", "counter", "HTTP attempts made to the Sec4Dev API.")
+	keys := make([][2]string, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	for _, k := range keys {
+		w.printf("sec4dev_requests_total{endpoint=%s,code=%s} %d\n", quoteLabel(k[0]), quoteLabel(k[1]), m.requests[k])
+	}
+
+	w.header("sec4dev_request_duration_seconds", "histogram", "Latency of HTTP attempts to the Sec4Dev API.")
+	for _, ep := range sortedKeys(m.latency) {
+		h := m.latency[ep]
+		for i, b := range m.buckets {
+			w.printf("sec4dev_request_duration_seconds_bucket{endpoint=%s,le=\"%s\"} %d\n", quoteLabel(ep), formatFloat(b), h.counts[i])
+		}
+		w.printf("sec4dev_request_duration_seconds_bucket{endpoint=%s,le=\"+Inf\"} %d\n", quoteLabel(ep), h.count)
+		w.printf("sec4dev_request_duration_seconds_sum{endpoint=%s} %s\n", quoteLabel(ep), formatFloat(h.sum))
+		w.printf("sec4dev_request_duration_seconds_count{endpoint=%s} %d\n", quoteLabel(ep), h.count)
+	}
+
+	w.counterVec("sec4dev_retries_total", "Retries performed after a failed attempt.", m.retries)
+	w.counterVec("sec4dev_rate_limited_total", "Responses with status 429.", m.rateLimited)
+	w.counterVec("sec4dev_overloaded_total", "Calls shed because the in-flight limit and queue were full.", m.overloaded)
+	w.counterVec("sec4dev_hedged_requests_total", "Hedged attempts sent because the first was slow.", m.hedges)
+	w.counterVec("sec4dev_coalesced_requests_total", "Calls that shared an identical in-flight request.", m.coalesced)
+	w.counterVec("sec4dev_cache_hits_total", "Results served from a cache.", m.cacheHits)
+	w.counterVec("sec4dev_cache_misses_total", "Cache lookups that required an API call.", m.cacheMisses)
+	w.counterVec("sec4dev_cache_stale_total", "Stale results served while revalidating or after an API failure.", m.cacheStale)
+
+	// With a KeyPool, each key's quota is labelled with its name.
+	names := make([]string, 0, len(m.quota))
+	for k := range m.quota {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, g := range []struct {
+		name, help string
+		value      func(RateLimitInfo) int
+	}{
+		{"sec4dev_quota_limit", "Last X-RateLimit-Limit value.", func(rl RateLimitInfo) int { return rl.Limit }},
+		{"sec4dev_quota_remaining", "Last X-RateLimit-Remaining value.", func(rl RateLimitInfo) int { return rl.Remaining }},
+		{"sec4dev_quota_reset_seconds", "Last X-RateLimit-Reset value.", func(rl RateLimitInfo) int { return rl.ResetSeconds }},
+	} {
+		if len(names) == 0 {
+			break
+		}
+		w.header(g.name, "gauge", g.help)
+		for _, k := range names {
+			if k == "" {
+				w.printf("%s %d\n", g.name, g.value(m.quota[k]))
+			} else {
+				w.printf("%s{key=%s} %d\n", g.name, quoteLabel(k), g.value(m.quota[k]))
+			}
+		}
+	}
+}
+
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (w *countingWriter) printf(format string, args ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(w.w, format, args...)
+	w.n += int64(n)
+	w.err = err
+}
+
+func (w *countingWriter) header(name, typ, help string) {
+	w.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func (w *countingWriter) counterVec(name, help string, values map[string]uint64) {
+	w.header(name, "counter", help)
+	for _, ep := range sortedKeys(values) {
+		w.printf("%s{endpoint=%s} %d\n", name, quoteLabel(ep), values[ep])
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,90 @@
+package sec4dev
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics_RecordsAttemptsAndRetries(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("X-RateLimit-Limit", "1000")
+		w.Header().Set("X-RateLimit-Remaining", "997")
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"email": "user@gmail.com", "domain": "gmail.com", "is_disposable": false,
+		})
+	}))
+	defer server.Close()
+
+	m := NewMetrics()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL+"/api/v1"), WithHTTPClient(server.Client()),
+		WithRetryDelay(1), WithMetrics(m))
+	if _, err := client.Email().Check(context.Background(), "user@gmail.com"); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	m.ObserveCacheHit("/email/check")
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	out, _ := io.ReadAll(rec.Body)
+	text := string(out)
+	for _, want := range []string{
+		`sec4dev_requests_total{endpoint="/email/check",code="200"} 1`,
+		`sec4dev_requests_total{endpoint="/email/check",code="502"} 1`,
+		`sec4dev_request_duration_seconds_count{endpoint="/email/check"} 2`,
+		`sec4dev_request_duration_seconds_bucket{endpoint="/email/check",le="+Inf"} 2`,
+		`sec4dev_retries_total{endpoint="/email/check"} 1`,
+		`sec4dev_cache_hits_total{endpoint="/email/check"} 1`,
+		`sec4dev_quota_remaining 997`,
+		`# TYPE sec4dev_request_duration_seconds histogram`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("missing %q in:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "sec4dev_cache_misses_total{") {
+		t.Errorf("cache misses counted without a cache:\n%s", text)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
+
+func TestMetrics_NilIsNoop(t *testing.T) {
+	var m *Metrics
+	m.ObserveCacheMiss("/ip/check")
+	m.observeAttempt("/ip/check", 200, 0)
+	var sb strings.Builder
+	if _, err := m.WriteTo(&sb); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	if sb.Len() != 0 {
+		t.Errorf("expected empty output, got %q", sb.String())
+	}
+}
+
+func TestQuoteLabel_Escapes(t *testing.T) {
+	if got := quoteLabel("a\"b\\c\nd"); got != `"a\"b\\c\nd"` {
+		t.Errorf("quoteLabel = %s", got)
+	}
+}
+
+func TestMetrics_QuotaKeyLabelEscaped(t *testing.T) {
+	m := NewMetrics()
+	m.observeRateLimit("clé\tb", RateLimitInfo{Limit: 100, Remaining: 40})
+	var sb strings.Builder
+	m.WriteTo(&sb)
+	if want := "sec4dev_quota_remaining{key=\"clé\tb\"} 40"; !strings.Contains(sb.String(), want) {
+		t.Errorf("missing %q in:\n%s", want, sb.String())
+	}
+}
@@ -1,10 +1,19 @@
 package sec4dev
 
+import "time"
+
 // EmailCheckResult is the result of an email check.
 type EmailCheckResult struct {
 	Email        string `json:"email"`
 	Domain       string `json:"domain"`
 	IsDisposable bool   `json:"is_disposable"`
+	// Overridden is OverrideAllow or OverrideDeny when the result came from
+	// WithOverrides instead of the API.
+	Overridden string `json:"overridden,omitempty"`
+	// Stale is set when the result was served from the cache past its TTL;
+	// Age is then how old it is.
+	Stale bool          `json:"stale,omitempty"`
+	Age   time.Duration `json:"-"`
 }
 
 // IPSignals holds signals from an IP check.
@@ -22,6 +31,8 @@ type IPNetwork struct {
 	ASN      *int   `json:"asn"`
 	Org      string `json:"org,omitempty"`
 	Provider string `json:"provider,omitempty"`
+	// Prefix is the announced prefix containing the IP, when reported.
+	Prefix string `json:"prefix,omitempty"`
 }
 
 // IPGeo holds geo info from an IP check.
@@ -38,6 +49,16 @@ type IPCheckResult struct {
 	Signals        IPSignals `json:"signals"`
 	Network        IPNetwork `json:"network"`
 	Geo            IPGeo     `json:"geo"`
+	// Overridden is OverrideAllow or OverrideDeny when the result came from
+	// WithOverrides instead of the API.
+	Overridden string `json:"overridden,omitempty"`
+	// Stale is set when the result was served from the cache past its TTL;
+	// Age is then how old it is.
+	Stale bool          `json:"stale,omitempty"`
+	Age   time.Duration `json:"-"`
+	// Partial is set by CheckPrefix when Network.Prefix does not cover the
+	// whole range asked about, so the result describes only part of it.
+	Partial bool `json:"partial,omitempty"`
 }
 
 // RateLimitInfo holds rate limit data from response headers.
@@ -0,0 +1,149 @@
+package sec4dev
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Values of EmailCheckResult.Overridden and IPCheckResult.Overridden.
+const (
+	OverrideAllow = "allow"
+	OverrideDeny  = "deny"
+)
+
+// OverrideConfig lists email domains and IPs whose checks are answered
+// locally instead of by the API.
+type OverrideConfig struct {
+	// AllowEmailDomains are reported as not disposable; DenyEmailDomains as
+	// disposable. A domain also matches its subdomains.
+	AllowEmailDomains []string
+	DenyEmailDomains  []string
+	// AllowIPs and DenyIPs hold IP addresses or CIDR prefixes. Allowed IPs
+	// are reported with classification "allowlisted" and no signals, denied
+	// IPs with classification "denylisted"; check Overridden to tell a
+	// denied IP from a clean one.
+	AllowIPs []string
+	DenyIPs  []string
+}
+
+// Overrides answers checks for configured email domains and IPs. When an
+// input matches both lists, the most specific entry wins: the longest
+// domain suffix or CIDR prefix. The same entry cannot be in both lists.
+type Overrides struct {
+	domains map[string]string
+	ips     PrefixTrie[string]
+}
+
+// NewOverrides validates cfg and builds the override lists. An entry that
+// appears in both the allow and deny lists is a ValidationError.
+func NewOverrides(cfg OverrideConfig) (*Overrides, error) {
+	o := &Overrides{domains: make(map[string]string)}
+	for _, list := range []struct {
+		domains []string
+		action  string
+	}{{cfg.AllowEmailDomains, OverrideAllow}, {cfg.DenyEmailDomains, OverrideDeny}} {
+		for _, d := range list.domains {
+			d = strings.Trim(strings.ToLower(strings.TrimSpace(d)), ".")
+			if d == "" {
+				return nil, &ValidationError{baseError("Override domain cannot be empty", 422, nil)}
+			}
+			if prev, ok := o.domains[d]; ok && prev != list.action {
+				return nil, &ValidationError{baseError(fmt.Sprintf("Domain %q is both allowed and denied", d), 422, nil)}
+			}
+			o.domains[d] = list.action
+		}
+	}
+	for _, list := range []struct {
+		ips    []string
+		action string
+	}{{cfg.AllowIPs, OverrideAllow}, {cfg.DenyIPs, OverrideDeny}} {
+		for _, s := range list.ips {
+			p, err := parseIPOrCIDR(s)
+			if err != nil {
+				return nil, err
+			}
+			p = p.Masked()
+			if found, prev, ok := o.ips.LookupPrefix(p); ok && found == p && prev != list.action {
+				return nil, &ValidationError{baseError(fmt.Sprintf("%s is both allowed and denied", p), 422, nil)}
+			}
+			o.ips.Insert(p, list.action)
+		}
+	}
+	return o, nil
+}
+
+// parseIPOrCIDR parses "203.0.113.7" as a single-address prefix or
+// "203.0.113.0/24" as a CIDR.
+func parseIPOrCIDR(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, &ValidationError{baseError(fmt.Sprintf("Invalid CIDR %q", s), 422, nil)}
+		}
+		return p, nil
+	}
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, &ValidationError{baseError(fmt.Sprintf("Invalid IP address %q", s), 422, nil)}
+	}
+	a = a.Unmap()
+	return netip.PrefixFrom(a, a.BitLen()), nil
+}
+
+// WithOverrides answers Check and CheckBatch for matching inputs from o
+// without calling the API. Results carry Overridden.
+func WithOverrides(o *Overrides) ClientOption {
+	return func(c *Client) {
+		c.overrides = o
+	}
+}
+
+// email returns a synthesized result if email's domain is overridden.
+func (o *Overrides) email(email string) (*EmailCheckResult, bool) {
+	if o == nil || len(o.domains) == 0 {
+		return nil, false
+	}
+	email = strings.TrimSpace(email)
+	domain := strings.ToLower(email[strings.LastIndex(email, "@")+1:])
+	for d := domain; d != ""; {
+		if action, ok := o.domains[d]; ok {
+			return &EmailCheckResult{
+				Email:        email,
+				Domain:       domain,
+				IsDisposable: action == OverrideDeny,
+				Overridden:   action,
+			}, true
+		}
+		_, d, _ = strings.Cut(d, ".")
+	}
+	return nil, false
+}
+
+// ip returns a synthesized result if ip falls in an overridden prefix.
+func (o *Overrides) ip(ip string) (*IPCheckResult, bool) {
+	ip = strings.TrimSpace(ip)
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil, false
+	}
+	return o.prefix(netip.PrefixFrom(addr, addr.BitLen()), ip)
+}
+
+// prefix returns a synthesized result, reported for ip, if all of p falls
+// in an overridden prefix.
+func (o *Overrides) prefix(p netip.Prefix, ip string) (*IPCheckResult, bool) {
+	if o == nil || o.ips.Len() == 0 {
+		return nil, false
+	}
+	_, action, ok := o.ips.LookupPrefix(p)
+	if !ok {
+		return nil, false
+	}
+	class := "allowlisted"
+	if action == OverrideDeny {
+		class = "denylisted"
+	}
+	return &IPCheckResult{IP: ip, Classification: class, Confidence: 1, Overridden: action}, true
+}
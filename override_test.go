@@ -0,0 +1,88 @@
+package sec4dev
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestOverrides_ShortCircuitCheck(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		emailHandler(w, r)
+	}))
+	defer server.Close()
+
+	o, err := NewOverrides(OverrideConfig{
+		AllowEmailDomains: []string{"Partner.example"},
+		DenyEmailDomains:  []string{"mail.partner.example", "throwaway.test"},
+		AllowIPs:          []string{"10.0.0.0/8", "2001:db8::/32"},
+		DenyIPs:           []string{"10.9.0.0/16", "198.51.100.7"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(0), WithOverrides(o))
+	ctx := context.Background()
+
+	emails := []struct {
+		in         string
+		overridden string
+		disposable bool
+	}{
+		{"a@partner.example", OverrideAllow, false},
+		{"a@eu.partner.example", OverrideAllow, false},
+		{"a@mail.partner.example", OverrideDeny, true},
+		{"a@x.throwaway.test", OverrideDeny, true},
+	}
+	for _, tc := range emails {
+		r, err := client.Email().Check(ctx, tc.in)
+		if err != nil || r.Overridden != tc.overridden || r.IsDisposable != tc.disposable {
+			t.Errorf("%s: %+v, %v", tc.in, r, err)
+		}
+	}
+	ips := []struct{ in, overridden, class string }{
+		{"10.1.2.3", OverrideAllow, "allowlisted"},
+		{"10.9.8.7", OverrideDeny, "denylisted"},
+		{"198.51.100.7", OverrideDeny, "denylisted"},
+		{"2001:db8::1", OverrideAllow, "allowlisted"},
+	}
+	for _, tc := range ips {
+		r, err := client.IP().Check(ctx, tc.in)
+		if err != nil || r.Overridden != tc.overridden || r.Classification != tc.class {
+			t.Errorf("%s: %+v, %v", tc.in, r, err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 0 {
+		t.Fatalf("overridden checks made %d API calls", n)
+	}
+
+	r, err := client.Email().Check(ctx, "user@gmail.com")
+	if err != nil || r.Overridden != "" || atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("unlisted email: %+v, %v", r, err)
+	}
+	batch, err := client.Email().CheckBatch(ctx, []string{"b@partner.example"})
+	if err != nil || batch[0].Result == nil || batch[0].Result.Overridden != OverrideAllow || atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("batch: %+v, %v", batch, err)
+	}
+}
+
+func TestNewOverrides_Invalid(t *testing.T) {
+	for _, cfg := range []OverrideConfig{
+		{AllowIPs: []string{"10.0.0.0/33"}},
+		{DenyIPs: []string{"not-an-ip"}},
+		{AllowEmailDomains: []string{" "}},
+		{AllowEmailDomains: []string{"partner.example"}, DenyEmailDomains: []string{"Partner.Example."}},
+		{AllowIPs: []string{"10.0.0.0/8"}, DenyIPs: []string{"10.1.2.3/8"}},
+		{AllowIPs: []string{"198.51.100.7"}, DenyIPs: []string{"198.51.100.7/32"}},
+	} {
+		if _, err := NewOverrides(cfg); err == nil {
+			t.Errorf("%+v accepted", cfg)
+		} else if _, ok := err.(*ValidationError); !ok {
+			t.Errorf("%+v: err = %T", cfg, err)
+		}
+	}
+}
@@ -0,0 +1,401 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// valueType is the static type of an expression.
+type valueType int
+
+const (
+	typeBool valueType = iota
+	typeNumber
+	typeString
+	typeList
+)
+
+func (t valueType) String() string {
+	switch t {
+	case typeBool:
+		return "bool"
+	case typeNumber:
+		return "number"
+	case typeString:
+		return "string"
+	}
+	return "list"
+}
+
+// node is a compiled expression. eval returns nil for missing values, bool,
+// float64, string, or []interface{} for lists.
+type node struct {
+	typ  valueType
+	elem valueType
+	eval func(*Input) interface{}
+}
+
+// compileExpr parses src into a boolean expression. usesRisk reports whether
+// it refers to risk.score.
+func compileExpr(src string) (n *node, usesRisk bool, err error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, false, err
+	}
+	p := &parser{toks: toks}
+	n, err = p.parseOr()
+	if err != nil {
+		return nil, false, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, false, p.errorf(t, "unexpected %s", t)
+	}
+	if n.typ != typeBool {
+		return nil, false, fmt.Errorf("expression is %s, want bool", n.typ)
+	}
+	return n, p.usesRisk, nil
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+var operators = []string{"||", "&&", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ","}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(src) && src[j] != c; j++ {
+				if src[j] == '\\' && j+1 < len(src) {
+					j++
+				}
+				sb.WriteByte(src[j])
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			toks = append(toks, token{tokString, sb.String(), i})
+			i = j + 1
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			j := i + 1
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{tokNumber, src[i:j], i})
+			i = j
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i + 1
+			for j < len(src) && (src[j] == '_' || src[j] == '.' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			toks = append(toks, token{tokIdent, src[i:j], i})
+			i = j
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					toks = append(toks, token{tokOp, op, i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+		}
+	}
+	return append(toks, token{tokEOF, "", len(src)}), nil
+}
+
+type parser struct {
+	toks     []token
+	pos      int
+	usesRisk bool
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(kind tokKind, text string) bool {
+	if t := p.peek(); t.kind == kind && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return fmt.Errorf("offset %d: %s", t.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) parseOr() (*node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if !p.accept(tokOp, "||") {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ != typeBool || right.typ != typeBool {
+			return nil, p.errorf(t, "|| needs bool operands")
+		}
+		l, r := left.eval, right.eval
+		left = &node{typ: typeBool, eval: func(in *Input) interface{} { return truthy(l(in)) || truthy(r(in)) }}
+	}
+}
+
+func (p *parser) parseAnd() (*node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if !p.accept(tokOp, "&&") {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ != typeBool || right.typ != typeBool {
+			return nil, p.errorf(t, "&& needs bool operands")
+		}
+		l, r := left.eval, right.eval
+		left = &node{typ: typeBool, eval: func(in *Input) interface{} { return truthy(l(in)) && truthy(r(in)) }}
+	}
+}
+
+func (p *parser) parseNot() (*node, error) {
+	t := p.peek()
+	if !p.accept(tokOp, "!") {
+		return p.parseCompare()
+	}
+	operand, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	if operand.typ != typeBool {
+		return nil, p.errorf(t, "! needs a bool operand")
+	}
+	e := operand.eval
+	// Negating a missing value leaves it missing, so the rule does not match.
+	return &node{typ: typeBool, eval: func(in *Input) interface{} {
+		v := e(in)
+		if v == nil {
+			return nil
+		}
+		return !truthy(v)
+	}}, nil
+}
+
+func (p *parser) parseCompare() (*node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.kind == tokIdent && (t.text == "in" || t.text == "not") {
+		p.next()
+		negate := t.text == "not"
+		if negate && !p.accept(tokIdent, "in") {
+			return nil, p.errorf(p.peek(), "expected in after not")
+		}
+		return p.finishIn(t, left, negate)
+	}
+	if t.kind != tokOp {
+		return left, nil
+	}
+	op := t.text
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return left, nil
+	}
+	p.next()
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if left.typ != right.typ || left.typ == typeList {
+		return nil, p.errorf(t, "cannot compare %s %s %s", left.typ, op, right.typ)
+	}
+	if op != "==" && op != "!=" && left.typ != typeNumber {
+		return nil, p.errorf(t, "%s needs number operands", op)
+	}
+	l, r := left.eval, right.eval
+	return &node{typ: typeBool, eval: func(in *Input) interface{} {
+		a, b := l(in), r(in)
+		if a == nil || b == nil {
+			return false
+		}
+		switch op {
+		case "==":
+			return a == b
+		case "!=":
+			return a != b
+		case "<":
+			return a.(float64) < b.(float64)
+		case "<=":
+			return a.(float64) <= b.(float64)
+		case ">":
+			return a.(float64) > b.(float64)
+		}
+		return a.(float64) >= b.(float64)
+	}}, nil
+}
+
+func (p *parser) finishIn(t token, left *node, negate bool) (*node, error) {
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if right.typ != typeList {
+		return nil, p.errorf(t, "in needs a list on the right")
+	}
+	if left.typ != right.elem {
+		return nil, p.errorf(t, "cannot look up %s in a list of %s", left.typ, right.elem)
+	}
+	l, r := left.eval, right.eval
+	return &node{typ: typeBool, eval: func(in *Input) interface{} {
+		v := l(in)
+		if v == nil {
+			return false
+		}
+		for _, item := range r(in).([]interface{}) {
+			if item == v {
+				return !negate
+			}
+		}
+		return negate
+	}}, nil
+}
+
+func (p *parser) parsePrimary() (*node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return constant(typeString, t.text), nil
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, p.errorf(t, "invalid number %s", t.text)
+		}
+		return constant(typeNumber, f), nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return constant(typeBool, true), nil
+		case "false":
+			return constant(typeBool, false), nil
+		}
+		f, ok := fields[t.text]
+		if !ok {
+			return nil, p.errorf(t, "unknown field %s", t.text)
+		}
+		if strings.HasPrefix(t.text, "risk.") {
+			p.usesRisk = true
+		}
+		return &node{typ: f.typ, eval: f.get}, nil
+	case tokOp:
+		switch t.text {
+		case "(":
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.accept(tokOp, ")") {
+				return nil, p.errorf(p.peek(), "expected )")
+			}
+			return n, nil
+		case "[":
+			return p.parseList(t)
+		}
+	}
+	return nil, p.errorf(t, "unexpected %s", t)
+}
+
+// parseList parses a list of literals after its opening bracket.
+func (p *parser) parseList(open token) (*node, error) {
+	var items []interface{}
+	elem := valueType(-1)
+	for !p.accept(tokOp, "]") {
+		if len(items) > 0 && !p.accept(tokOp, ",") {
+			return nil, p.errorf(p.peek(), "expected , or ]")
+		}
+		t := p.peek()
+		literal := t.kind == tokString || t.kind == tokNumber || t.kind == tokIdent && (t.text == "true" || t.text == "false")
+		if !literal {
+			return nil, p.errorf(t, "list items must be literals")
+		}
+		item, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		v := item.eval(nil)
+		if elem >= 0 && item.typ != elem {
+			return nil, p.errorf(open, "list mixes %s and %s", elem, item.typ)
+		}
+		elem = item.typ
+		items = append(items, v)
+	}
+	if elem < 0 {
+		elem = typeString
+	}
+	return &node{typ: typeList, elem: elem, eval: func(*Input) interface{} { return items }}, nil
+}
+
+func constant(typ valueType, v interface{}) *node {
+	return &node{typ: typ, eval: func(*Input) interface{} { return v }}
+}
+
+func truthy(v interface{}) bool {
+	b, _ := v.(bool)
+	return b
+}
@@ -0,0 +1,74 @@
+package policy
+
+import "github.com/sec4dev/sec4dev-go"
+
+type field struct {
+	typ valueType
+	get func(*Input) interface{}
+}
+
+func emailField(typ valueType, get func(*sec4dev.EmailCheckResult) interface{}) field {
+	return field{typ, func(in *Input) interface{} {
+		if in.Email == nil {
+			return nil
+		}
+		return get(in.Email)
+	}}
+}
+
+func ipField(typ valueType, get func(*sec4dev.IPCheckResult) interface{}) field {
+	return field{typ, func(in *Input) interface{} {
+		if in.IP == nil {
+			return nil
+		}
+		return get(in.IP)
+	}}
+}
+
+// optional treats an empty string as a missing value, so rules such as
+// ip.geo.country not in [...] do not fire when the API has no data.
+func optional(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// fields are the names an expression may refer to. They mirror the API's
+// JSON field names.
+var fields = map[string]field{
+	"email.email":         emailField(typeString, func(r *sec4dev.EmailCheckResult) interface{} { return r.Email }),
+	"email.domain":        emailField(typeString, func(r *sec4dev.EmailCheckResult) interface{} { return r.Domain }),
+	"email.is_disposable": emailField(typeBool, func(r *sec4dev.EmailCheckResult) interface{} { return r.IsDisposable }),
+	"email.overridden":    emailField(typeString, func(r *sec4dev.EmailCheckResult) interface{} { return optional(r.Overridden) }),
+	"email.checked":       {typeBool, func(in *Input) interface{} { return in.Email != nil }},
+
+	"ip.ip":                     ipField(typeString, func(r *sec4dev.IPCheckResult) interface{} { return r.IP }),
+	"ip.classification":         ipField(typeString, func(r *sec4dev.IPCheckResult) interface{} { return r.Classification }),
+	"ip.confidence":             ipField(typeNumber, func(r *sec4dev.IPCheckResult) interface{} { return r.Confidence }),
+	"ip.signals.is_hosting":     ipField(typeBool, func(r *sec4dev.IPCheckResult) interface{} { return r.Signals.IsHosting }),
+	"ip.signals.is_residential": ipField(typeBool, func(r *sec4dev.IPCheckResult) interface{} { return r.Signals.IsResidential }),
+	"ip.signals.is_mobile":      ipField(typeBool, func(r *sec4dev.IPCheckResult) interface{} { return r.Signals.IsMobile }),
+	"ip.signals.is_vpn":         ipField(typeBool, func(r *sec4dev.IPCheckResult) interface{} { return r.Signals.IsVPN }),
+	"ip.signals.is_tor":         ipField(typeBool, func(r *sec4dev.IPCheckResult) interface{} { return r.Signals.IsTor }),
+	"ip.signals.is_proxy":       ipField(typeBool, func(r *sec4dev.IPCheckResult) interface{} { return r.Signals.IsProxy }),
+	"ip.network.asn": ipField(typeNumber, func(r *sec4dev.IPCheckResult) interface{} {
+		if r.Network.ASN == nil {
+			return nil
+		}
+		return float64(*r.Network.ASN)
+	}),
+	"ip.network.org":      ipField(typeString, func(r *sec4dev.IPCheckResult) interface{} { return optional(r.Network.Org) }),
+	"ip.network.provider": ipField(typeString, func(r *sec4dev.IPCheckResult) interface{} { return optional(r.Network.Provider) }),
+	"ip.geo.country":      ipField(typeString, func(r *sec4dev.IPCheckResult) interface{} { return optional(r.Geo.Country) }),
+	"ip.geo.region":       ipField(typeString, func(r *sec4dev.IPCheckResult) interface{} { return optional(r.Geo.Region) }),
+	"ip.overridden":       ipField(typeString, func(r *sec4dev.IPCheckResult) interface{} { return optional(r.Overridden) }),
+	"ip.checked":          {typeBool, func(in *Input) interface{} { return in.IP != nil }},
+
+	"risk.score": {typeNumber, func(in *Input) interface{} {
+		if in.Risk == nil {
+			return nil
+		}
+		return in.Risk.Score
+	}},
+}
@@ -0,0 +1,213 @@
+// Package policy evaluates declarative allow/challenge/deny rules against
+// Sec4Dev check results, so blocking policy can change without a redeploy.
+//
+// A rule is a boolean expression over the check results:
+//
+//	ip.signals.is_tor || (ip.classification == "hosting" && ip.confidence > 0.8)
+//	email.is_disposable && ip.geo.country not in ["US", "CA"]
+//	risk.score >= 70
+//
+// Fields follow the API's JSON names under email., ip. and risk.score.
+// Operators are ||, &&, !, ==, !=, <, <=, >, >=, in and not in, with
+// parentheses for grouping; lists hold string, number or bool literals.
+// Expressions are type-checked when compiled. A field whose result was not
+// provided, or an optional string field the API left empty, is missing:
+// every comparison on it is false, and so is ! applied to it. email.checked and ip.checked are never
+// missing; they are false when that result was not provided, e.g. because
+// the check failed.
+//
+// Rules are checked in order and the first match decides. The text format
+// has one rule per line, continued on indented lines, plus an optional
+// default:
+//
+//	# comments start with #
+//	deny tor_exit: ip.signals.is_tor
+//	challenge cloud_signup: ip.signals.is_hosting
+//	    && email.is_disposable
+//	default allow
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sec4dev/sec4dev-go"
+	"github.com/sec4dev/sec4dev-go/risk"
+)
+
+// Action is the outcome of a policy.
+type Action string
+
+// Actions, from least to most severe.
+const (
+	Allow     Action = "allow"
+	Challenge Action = "challenge"
+	Deny      Action = "deny"
+)
+
+func (a Action) valid() bool {
+	return a == Allow || a == Challenge || a == Deny
+}
+
+// Rule is an uncompiled policy rule.
+type Rule struct {
+	Name   string `json:"name"`
+	Action Action `json:"action"`
+	When   string `json:"when"`
+}
+
+// Input is what rules are evaluated against. Any field may be nil.
+type Input struct {
+	Email *sec4dev.EmailCheckResult
+	IP    *sec4dev.IPCheckResult
+	Risk  *risk.Assessment
+}
+
+// Decision is the result of evaluating a policy. Rule is the name of the
+// matching rule, or empty when the default applied.
+type Decision struct {
+	Action Action
+	Rule   string
+}
+
+// Policy is a compiled, ordered rule set. It is safe for concurrent use.
+type Policy struct {
+	rules         []compiledRule
+	defaultAction Action
+	usesRisk      bool
+	engine        *risk.Engine
+}
+
+type compiledRule struct {
+	Rule
+	expr *node
+}
+
+// Compile compiles rules. defaultAction applies when no rule matches and
+// defaults to Allow.
+func Compile(rules []Rule, defaultAction Action) (*Policy, error) {
+	if defaultAction == "" {
+		defaultAction = Allow
+	}
+	if !defaultAction.valid() {
+		return nil, fmt.Errorf("policy: invalid default action %q", defaultAction)
+	}
+	p := &Policy{defaultAction: defaultAction}
+	seen := make(map[string]bool)
+	for _, r := range rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("policy: rule with empty name")
+		}
+		if seen[r.Name] {
+			return nil, fmt.Errorf("policy: duplicate rule %q", r.Name)
+		}
+		seen[r.Name] = true
+		if !r.Action.valid() {
+			return nil, fmt.Errorf("policy: rule %q: invalid action %q", r.Name, r.Action)
+		}
+		expr, usesRisk, err := compileExpr(r.When)
+		if err != nil {
+			return nil, fmt.Errorf("policy: rule %q: %w", r.Name, err)
+		}
+		p.usesRisk = p.usesRisk || usesRisk
+		p.rules = append(p.rules, compiledRule{Rule: r, expr: expr})
+	}
+	return p, nil
+}
+
+// ParseJSON compiles a policy from {"default": "allow", "rules": [{"name":
+// ..., "action": ..., "when": ...}]}.
+func ParseJSON(data []byte) (*Policy, error) {
+	var doc struct {
+		Default Action `json:"default"`
+		Rules   []Rule `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("policy: %w", err)
+	}
+	return Compile(doc.Rules, doc.Default)
+}
+
+// Parse compiles a policy from the text format described in the package
+// documentation.
+func Parse(text string) (*Policy, error) {
+	var rules []Rule
+	var def Action
+	for n, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if len(rules) == 0 {
+				return nil, fmt.Errorf("policy: line %d: continuation without a rule", n+1)
+			}
+			rules[len(rules)-1].When += " " + trimmed
+			continue
+		}
+		head, expr, found := strings.Cut(trimmed, ":")
+		words := strings.Fields(head)
+		switch {
+		case !found && len(words) == 2 && words[0] == "default":
+			def = Action(words[1])
+		case found && len(words) == 2:
+			rules = append(rules, Rule{Action: Action(words[0]), Name: words[1], When: strings.TrimSpace(expr)})
+		default:
+			return nil, fmt.Errorf("policy: line %d: want \"<action> <name>: <expression>\" or \"default <action>\"", n+1)
+		}
+	}
+	return Compile(rules, def)
+}
+
+// Evaluate returns the action of the first rule matching in, or the
+// default action.
+func (p *Policy) Evaluate(in Input) Decision {
+	for _, r := range p.rules {
+		if truthy(r.expr.eval(&in)) {
+			return Decision{Action: r.Action, Rule: r.Name}
+		}
+	}
+	return Decision{Action: p.defaultAction}
+}
+
+// Rules returns the policy's rules in evaluation order.
+func (p *Policy) Rules() []Rule {
+	out := make([]Rule, len(p.rules))
+	for i, r := range p.rules {
+		out[i] = r.Rule
+	}
+	return out
+}
+
+// WithRiskEngine returns a copy of p that scores risk.score with e in
+// DecideSignup instead of the default engine.
+func (p *Policy) WithRiskEngine(e *risk.Engine) *Policy {
+	cp := *p
+	cp.engine = e
+	return &cp
+}
+
+// DecideSignup evaluates p against the results of Client.AssessSignup,
+// scoring risk first, with the signup's countries, when a rule refers to
+// risk.score. It lets a Policy be passed to sec4dev.WithSignupPolicy.
+func (p *Policy) DecideSignup(signup sec4dev.SignupInput) sec4dev.SignupDecision {
+	in := Input{Email: signup.Email, IP: signup.IP}
+	if p.usesRisk {
+		e := p.engine
+		if e == nil {
+			e = defaultEngine
+		}
+		a := e.Score(risk.Input{
+			Email:            signup.Email,
+			IP:               signup.IP,
+			ExpectedCountry:  signup.ExpectedCountry,
+			AllowedCountries: signup.AllowedCountries,
+		})
+		in.Risk = &a
+	}
+	d := p.Evaluate(in)
+	return sec4dev.SignupDecision{Action: string(d.Action), Rule: d.Rule}
+}
+
+var defaultEngine = risk.New(risk.Config{})
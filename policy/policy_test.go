@@ -0,0 +1,177 @@
+package policy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sec4dev/sec4dev-go"
+	"github.com/sec4dev/sec4dev-go/risk"
+)
+
+const examplePolicy = `
+# Block anonymizers outright.
+deny tor_or_cloud: ip.signals.is_tor || (ip.classification == "hosting" && ip.confidence > 0.8)
+challenge foreign_disposable: email.is_disposable
+    && ip.geo.country not in ["US", "CA"]
+challenge risky: risk.score >= 70
+default allow
+`
+
+func TestParse_Evaluate(t *testing.T) {
+	p, err := Parse(examplePolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name string
+		in   Input
+		want Decision
+	}{
+		{"tor", Input{IP: &sec4dev.IPCheckResult{Signals: sec4dev.IPSignals{IsTor: true}}}, Decision{Deny, "tor_or_cloud"}},
+		{"confident hosting", Input{IP: &sec4dev.IPCheckResult{Classification: "hosting", Confidence: 0.9}}, Decision{Deny, "tor_or_cloud"}},
+		{"unsure hosting", Input{IP: &sec4dev.IPCheckResult{Classification: "hosting", Confidence: 0.5}}, Decision{Action: Allow}},
+		{"disposable abroad", Input{
+			Email: &sec4dev.EmailCheckResult{IsDisposable: true},
+			IP:    &sec4dev.IPCheckResult{Geo: sec4dev.IPGeo{Country: "BR"}},
+		}, Decision{Challenge, "foreign_disposable"}},
+		{"disposable at home", Input{
+			Email: &sec4dev.EmailCheckResult{IsDisposable: true},
+			IP:    &sec4dev.IPCheckResult{Geo: sec4dev.IPGeo{Country: "CA"}},
+		}, Decision{Action: Allow}},
+		{"disposable no country", Input{
+			Email: &sec4dev.EmailCheckResult{IsDisposable: true},
+			IP:    &sec4dev.IPCheckResult{},
+		}, Decision{Action: Allow}},
+		{"high score", Input{Risk: &risk.Assessment{Score: 80}}, Decision{Challenge, "risky"}},
+		{"nothing", Input{}, Decision{Action: Allow}},
+	}
+	for _, tc := range cases {
+		if got := p.Evaluate(tc.in); got != tc.want {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	p, err := ParseJSON([]byte(`{
+		"default": "challenge",
+		"rules": [
+			{"name": "big_asn", "action": "deny", "when": "ip.network.asn in [16509, 14618]"},
+			{"name": "trusted", "action": "allow", "when": "email.domain == 'example.com' && !ip.signals.is_vpn"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	asn := 16509
+	if d := p.Evaluate(Input{IP: &sec4dev.IPCheckResult{Network: sec4dev.IPNetwork{ASN: &asn}}}); d != (Decision{Deny, "big_asn"}) {
+		t.Errorf("asn: %+v", d)
+	}
+	in := Input{Email: &sec4dev.EmailCheckResult{Domain: "example.com"}, IP: &sec4dev.IPCheckResult{}}
+	if d := p.Evaluate(in); d != (Decision{Allow, "trusted"}) {
+		t.Errorf("trusted: %+v", d)
+	}
+	if d := p.Evaluate(Input{}); d != (Decision{Action: Challenge}) {
+		t.Errorf("default: %+v", d)
+	}
+	if rules := p.Rules(); len(rules) != 2 || rules[0].Name != "big_asn" {
+		t.Errorf("Rules() = %+v", rules)
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	cases := []struct {
+		rule Rule
+		want string
+	}{
+		{Rule{Name: "a", Action: Deny, When: "ip.signals.is_tour"}, "unknown field"},
+		{Rule{Name: "a", Action: Deny, When: `ip.confidence == "high"`}, "cannot compare"},
+		{Rule{Name: "a", Action: Deny, When: "ip.classification"}, "want bool"},
+		{Rule{Name: "a", Action: Deny, When: `ip.classification > "a"`}, "needs number"},
+		{Rule{Name: "a", Action: Deny, When: `ip.geo.country in ["US", 1]`}, "mixes"},
+		{Rule{Name: "a", Action: Deny, When: `ip.confidence in ["US"]`}, "cannot look up"},
+		{Rule{Name: "a", Action: Deny, When: `(ip.signals.is_tor`}, "expected )"},
+		{Rule{Name: "a", Action: Deny, When: `email.domain == "x`}, "unterminated"},
+		{Rule{Name: "a", Action: "block", When: "true"}, "invalid action"},
+		{Rule{Action: Deny, When: "true"}, "empty name"},
+	}
+	for _, tc := range cases {
+		_, err := Compile([]Rule{tc.rule}, Allow)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%q: err = %v, want %q", tc.rule.When, err, tc.want)
+		}
+	}
+	if _, err := Compile([]Rule{{"a", Deny, "true"}, {"a", Allow, "true"}}, Allow); err == nil {
+		t.Error("duplicate names accepted")
+	}
+	if _, err := Parse("deny: true"); err == nil {
+		t.Error("rule without name accepted")
+	}
+	if _, err := Parse("  && true"); err == nil {
+		t.Error("leading continuation accepted")
+	}
+}
+
+func TestDecideSignup_ScoresRisk(t *testing.T) {
+	p, err := Parse("deny risky: risk.score >= 50\nallow clean: !email.is_disposable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var _ sec4dev.SignupPolicy = p
+	email := &sec4dev.EmailCheckResult{IsDisposable: true}
+	if d := p.DecideSignup(sec4dev.SignupInput{Email: email}); d != (sec4dev.SignupDecision{Action: "deny", Rule: "risky"}) {
+		t.Errorf("default engine: %+v", d)
+	}
+	lenient := p.WithRiskEngine(risk.New(risk.Config{Weights: risk.Weights{risk.SignalDisposableEmail: 10}}))
+	if d := lenient.DecideSignup(sec4dev.SignupInput{Email: email}); d != (sec4dev.SignupDecision{Action: "allow"}) {
+		t.Errorf("custom engine: %+v", d)
+	}
+	if d := p.DecideSignup(sec4dev.SignupInput{Email: &sec4dev.EmailCheckResult{}}); d.Rule != "clean" {
+		t.Errorf("clean: %+v", d)
+	}
+
+	// The signup's countries reach the risk engine.
+	ip := &sec4dev.IPCheckResult{IP: "203.0.113.7", Confidence: 0.9, Geo: sec4dev.IPGeo{Country: "BR"}}
+	geo, _ := Parse("deny geo: risk.score >= 50")
+	if d := geo.DecideSignup(sec4dev.SignupInput{IP: ip}); d.Action != "allow" {
+		t.Errorf("no countries: %+v", d)
+	}
+	in := sec4dev.SignupInput{IP: ip, ExpectedCountry: "US", AllowedCountries: []string{"US", "CA"}}
+	if d := geo.DecideSignup(in); d.Rule != "geo" {
+		t.Errorf("countries: %+v, want the geo signals scored", d)
+	}
+}
+
+func TestDecideSignup_MissingChecks(t *testing.T) {
+	p, err := Parse("challenge no_ip: !ip.checked\ndeny no_email: !email.checked\nallow ok: ip.checked && email.checked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	email, ip := &sec4dev.EmailCheckResult{}, &sec4dev.IPCheckResult{}
+	for _, tc := range []struct {
+		in   sec4dev.SignupInput
+		want string
+	}{
+		{sec4dev.SignupInput{Email: email, IPErr: errors.New("timeout")}, "no_ip"},
+		{sec4dev.SignupInput{IP: ip, EmailErr: errors.New("timeout")}, "no_email"},
+		{sec4dev.SignupInput{Email: email, IP: ip}, "ok"},
+	} {
+		if d := p.DecideSignup(tc.in); d.Rule != tc.want {
+			t.Errorf("%+v: rule %q, want %q", tc.in, d.Rule, tc.want)
+		}
+	}
+}
+
+func TestEvaluate_NotOnMissing(t *testing.T) {
+	p, err := Parse("allow clean_ip: !ip.signals.is_tor\ndefault deny")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := p.Evaluate(Input{}); d != (Decision{Action: Deny}) {
+		t.Errorf("no IP result: %+v, want the default deny", d)
+	}
+	if d := p.Evaluate(Input{IP: &sec4dev.IPCheckResult{}}); d != (Decision{Allow, "clean_ip"}) {
+		t.Errorf("clean IP: %+v, want clean_ip", d)
+	}
+}
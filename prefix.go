@@ -0,0 +1,253 @@
+package sec4dev
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PrefixTrie maps IP prefixes to values and finds the longest stored prefix
+// containing an address. IPv4-mapped IPv6 addresses are treated as IPv4. It
+// is safe for concurrent reads but not for writes concurrent with anything.
+type PrefixTrie[V any] struct {
+	v4, v6 *trieNode[V]
+	n      int
+}
+
+type trieNode[V any] struct {
+	child  [2]*trieNode[V]
+	prefix netip.Prefix
+	value  V
+	set    bool
+}
+
+func addrBit(b []byte, i int) int {
+	return int(b[i/8]>>(7-i%8)) & 1
+}
+
+// unmapPrefix masks p and turns an IPv4-mapped IPv6 prefix into IPv4.
+func unmapPrefix(p netip.Prefix) netip.Prefix {
+	if a := p.Addr(); a.Is4In6() && p.Bits() >= 96 {
+		return netip.PrefixFrom(a.Unmap(), p.Bits()-96).Masked()
+	}
+	return p.Masked()
+}
+
+func (t *PrefixTrie[V]) root(a netip.Addr) **trieNode[V] {
+	if a.Is4() {
+		return &t.v4
+	}
+	return &t.v6
+}
+
+// Insert stores v under p, replacing any value already stored for it.
+// Invalid prefixes are ignored.
+func (t *PrefixTrie[V]) Insert(p netip.Prefix, v V) {
+	p = unmapPrefix(p)
+	if !p.IsValid() {
+		return
+	}
+	root := t.root(p.Addr())
+	if *root == nil {
+		*root = &trieNode[V]{}
+	}
+	n, b := *root, p.Addr().AsSlice()
+	for i := 0; i < p.Bits(); i++ {
+		bit := addrBit(b, i)
+		if n.child[bit] == nil {
+			n.child[bit] = &trieNode[V]{}
+		}
+		n = n.child[bit]
+	}
+	if !n.set {
+		t.n++
+	}
+	n.prefix, n.value, n.set = p, v, true
+}
+
+// Delete removes p and reports whether it was present.
+func (t *PrefixTrie[V]) Delete(p netip.Prefix) bool {
+	p = unmapPrefix(p)
+	if !p.IsValid() {
+		return false
+	}
+	path := []*trieNode[V]{*t.root(p.Addr())}
+	b := p.Addr().AsSlice()
+	for i := 0; i < p.Bits() && path[i] != nil; i++ {
+		path = append(path, path[i].child[addrBit(b, i)])
+	}
+	n := path[len(path)-1]
+	if n == nil || !n.set {
+		return false
+	}
+	var zero V
+	n.value, n.set = zero, false
+	t.n--
+	// Prune nodes left without a value or children.
+	for i := len(path) - 1; i > 0; i-- {
+		if n := path[i]; n.set || n.child[0] != nil || n.child[1] != nil {
+			break
+		}
+		path[i-1].child[addrBit(b, i-1)] = nil
+	}
+	return true
+}
+
+// Lookup returns the longest stored prefix containing a and its value.
+func (t *PrefixTrie[V]) Lookup(a netip.Addr) (netip.Prefix, V, bool) {
+	a = a.Unmap()
+	if !a.IsValid() {
+		var zero V
+		return netip.Prefix{}, zero, false
+	}
+	return t.longest(a, a.BitLen())
+}
+
+// LookupPrefix returns the longest stored prefix covering all of p.
+func (t *PrefixTrie[V]) LookupPrefix(p netip.Prefix) (netip.Prefix, V, bool) {
+	p = unmapPrefix(p)
+	if !p.IsValid() {
+		var zero V
+		return netip.Prefix{}, zero, false
+	}
+	return t.longest(p.Addr(), p.Bits())
+}
+
+func (t *PrefixTrie[V]) longest(a netip.Addr, maxBits int) (netip.Prefix, V, bool) {
+	var best *trieNode[V]
+	n, b := *t.root(a), a.AsSlice()
+	for i := 0; n != nil; i++ {
+		if n.set {
+			best = n
+		}
+		if i == maxBits {
+			break
+		}
+		n = n.child[addrBit(b, i)]
+	}
+	if best == nil {
+		var zero V
+		return netip.Prefix{}, zero, false
+	}
+	return best.prefix, best.value, true
+}
+
+// Len returns the number of stored prefixes.
+func (t *PrefixTrie[V]) Len() int {
+	return t.n
+}
+
+// prefixCache holds IP results that apply to a whole prefix.
+type prefixCache struct {
+	mu   sync.Mutex
+	ttl  time.Duration
+	trie PrefixTrie[prefixEntry]
+}
+
+type prefixEntry struct {
+	result    IPCheckResult
+	expiresAt time.Time
+}
+
+// WithPrefixCache answers IP checks from earlier results for the same
+// announced prefix for ttl. Only network-wide results are reused: a result
+// the API classifies as hosting covers its whole Network.Prefix.
+func WithPrefixCache(ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.prefixes = &prefixCache{ttl: ttl}
+	}
+}
+
+// lookup returns a result for the longest live prefix covering p.
+func (pc *prefixCache) lookup(p netip.Prefix) (*IPCheckResult, bool) {
+	if pc == nil {
+		return nil, false
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	for {
+		found, e, ok := pc.trie.LookupPrefix(p)
+		if !ok {
+			return nil, false
+		}
+		if time.Now().Before(e.expiresAt) {
+			r := e.result
+			return &r, true
+		}
+		pc.trie.Delete(found)
+	}
+}
+
+func (pc *prefixCache) add(p netip.Prefix, r *IPCheckResult) {
+	if pc == nil || pc.ttl <= 0 {
+		return
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.trie.Insert(p, prefixEntry{result: *r, expiresAt: time.Now().Add(pc.ttl)})
+}
+
+// learn records r for its announced prefix if the classification is
+// network-wide and the prefix is not already cached.
+func (pc *prefixCache) learn(r *IPCheckResult) {
+	if pc == nil || r.Classification != "hosting" || r.Overridden != "" || r.Stale {
+		return
+	}
+	p, err := netip.ParsePrefix(r.Network.Prefix)
+	if err != nil {
+		return
+	}
+	p = unmapPrefix(p)
+	pc.mu.Lock()
+	found, e, ok := pc.trie.LookupPrefix(p)
+	pc.mu.Unlock()
+	if ok && found == p && time.Now().Before(e.expiresAt) {
+		return
+	}
+	pc.add(p, r)
+}
+
+// CheckPrefix classifies an IP range such as "203.0.113.0/24" by checking
+// its first address. With WithPrefixCache, a cached result for an enclosing
+// announced prefix is returned without a call, and a hosting result is
+// cached for the announced prefix the API reports, never for the range
+// passed in. When the announced prefix is narrower than the range, the
+// result is marked Partial.
+func (s *IPService) CheckPrefix(ctx context.Context, prefix string) (*IPCheckResult, error) {
+	p, err := netip.ParsePrefix(strings.TrimSpace(prefix))
+	if err != nil {
+		return nil, &ValidationError{baseError("Invalid CIDR prefix", 422, nil)}
+	}
+	p = p.Masked()
+	if err := ValidateIP(p.Addr().String()); err != nil {
+		return nil, err
+	}
+	if r, ok := s.client.overrides.prefix(p, p.Addr().String()); ok {
+		return r, nil
+	}
+	if r, ok := s.client.prefixes.lookup(p); ok {
+		s.client.metrics.ObserveCacheHit("/ip/check")
+		r.IP = p.Addr().String()
+		return r, nil
+	}
+	r, err := s.check(ctx, p.Addr().String())
+	if err != nil {
+		return nil, err
+	}
+	s.client.prefixes.learn(r)
+	r.Partial = !coversPrefix(r.Network.Prefix, p)
+	return r, nil
+}
+
+// coversPrefix reports whether the announced prefix contains all of p. An
+// empty or unparsable announced prefix is taken to cover it.
+func coversPrefix(announced string, p netip.Prefix) bool {
+	np, err := netip.ParsePrefix(announced)
+	if err != nil {
+		return true
+	}
+	np, p = unmapPrefix(np), unmapPrefix(p)
+	return np.Bits() <= p.Bits() && np.Contains(p.Addr())
+}
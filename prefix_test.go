@@ -0,0 +1,156 @@
+package sec4dev
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPrefixTrie_LongestMatch(t *testing.T) {
+	var trie PrefixTrie[string]
+	trie.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trie.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+	trie.Insert(netip.MustParsePrefix("10.1.2.3/24"), "c") // masked to 10.1.2.0/24
+	trie.Insert(netip.MustParsePrefix("2001:db8::/32"), "v6")
+	if trie.Len() != 4 {
+		t.Fatalf("Len = %d", trie.Len())
+	}
+
+	cases := []struct{ addr, prefix, value string }{
+		{"10.9.9.9", "10.0.0.0/8", "a"},
+		{"10.1.9.9", "10.1.0.0/16", "b"},
+		{"10.1.2.200", "10.1.2.0/24", "c"},
+		{"::ffff:10.1.2.7", "10.1.2.0/24", "c"},
+		{"2001:db8:1::1", "2001:db8::/32", "v6"},
+	}
+	for _, tc := range cases {
+		p, v, ok := trie.Lookup(netip.MustParseAddr(tc.addr))
+		if !ok || p.String() != tc.prefix || v != tc.value {
+			t.Errorf("Lookup(%s) = %s, %q, %v", tc.addr, p, v, ok)
+		}
+	}
+	if _, _, ok := trie.Lookup(netip.MustParseAddr("192.0.2.1")); ok {
+		t.Error("Lookup matched an unrelated address")
+	}
+	if p, _, ok := trie.LookupPrefix(netip.MustParsePrefix("10.1.0.0/20")); !ok || p.String() != "10.1.0.0/16" {
+		t.Errorf("LookupPrefix = %s, %v", p, ok)
+	}
+
+	if !trie.Delete(netip.MustParsePrefix("10.1.2.0/24")) || trie.Delete(netip.MustParsePrefix("10.1.2.0/24")) {
+		t.Error("Delete did not report presence correctly")
+	}
+	if p, _, _ := trie.Lookup(netip.MustParseAddr("10.1.2.200")); p.String() != "10.1.0.0/16" {
+		t.Errorf("after Delete, Lookup = %s", p)
+	}
+	if trie.Len() != 3 {
+		t.Errorf("Len = %d", trie.Len())
+	}
+}
+
+func TestPrefixCache_AnswersNeighbors(t *testing.T) {
+	var mu sync.Mutex
+	var checked []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			IP string `json:"ip"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		checked = append(checked, body.IP)
+		mu.Unlock()
+		class, prefix := "residential", "198.51.100.0/24"
+		if strings.HasPrefix(body.IP, "203.0.113.") {
+			class, prefix = "hosting", "203.0.113.0/24"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ip": body.IP, "classification": class, "confidence": 0.95,
+			"network": map[string]interface{}{"asn": 16509, "provider": "AWS", "prefix": prefix},
+		})
+	}))
+	defer server.Close()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(0), WithPrefixCache(time.Minute))
+	ip := client.IP()
+	ctx := context.Background()
+
+	for _, addr := range []string{"203.0.113.10", "203.0.113.99", "198.51.100.1", "198.51.100.2"} {
+		r, err := ip.Check(ctx, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.IP != addr {
+			t.Errorf("IP = %q, want %q", r.IP, addr)
+		}
+	}
+	want := []string{"203.0.113.10", "198.51.100.1", "198.51.100.2"}
+	if strings.Join(checked, ",") != strings.Join(want, ",") {
+		t.Errorf("checked %v, want %v", checked, want)
+	}
+
+	checked = nil
+	// A range inside a learned hosting prefix is answered from the cache.
+	r, err := ip.CheckPrefix(ctx, "203.0.113.128/25")
+	if err != nil || r.IP != "203.0.113.128" || r.Classification != "hosting" {
+		t.Fatalf("CheckPrefix(/25) = %+v, %v", r, err)
+	}
+	// The range passed in is never cached: a residential answer for
+	// 0.0.0.0/0 must not cover other addresses.
+	if r, err := ip.CheckPrefix(ctx, "0.0.0.0/0"); err != nil || r.IP != "0.0.0.0" {
+		t.Fatalf("CheckPrefix(0.0.0.0/0) = %+v, %v", r, err)
+	} else if !r.Partial {
+		t.Error("a /24 answer for 0.0.0.0/0 not marked Partial")
+	}
+	if r.Partial {
+		t.Error("a /25 inside a learned /24 marked Partial")
+	}
+	if _, err := ip.Check(ctx, "192.0.2.77"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "0.0.0.0,192.0.2.77"; strings.Join(checked, ",") != want {
+		t.Errorf("checked %v, want %s", checked, want)
+	}
+
+	if _, err := ip.CheckPrefix(ctx, "192.0.2.0/33"); err == nil {
+		t.Error("invalid prefix accepted")
+	} else if _, ok := err.(*ValidationError); !ok {
+		t.Errorf("err = %T", err)
+	}
+}
+
+func TestCheckPrefix_MarksPartialCoverage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			IP string `json:"ip"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ip": body.IP, "classification": "hosting",
+			"network": map[string]interface{}{"prefix": "203.0.0.0/24"},
+		})
+	}))
+	defer server.Close()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(0))
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		prefix  string
+		partial bool
+	}{
+		{"203.0.0.0/16", true},
+		{"203.0.0.0/24", false},
+		{"203.0.0.64/26", false},
+	} {
+		r, err := client.IP().CheckPrefix(ctx, tc.prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Partial != tc.partial {
+			t.Errorf("CheckPrefix(%s).Partial = %v, want %v", tc.prefix, r.Partial, tc.partial)
+		}
+	}
+}
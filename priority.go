@@ -0,0 +1,56 @@
+package sec4dev
+
+import "context"
+
+// Priority orders calls waiting for the client-side rate limiter or the
+// bulkhead. Interactive calls are served before any queued background call.
+type Priority int
+
+const (
+	// PriorityInteractive is for calls a user is waiting on. It is the
+	// default.
+	PriorityInteractive Priority = iota
+	// PriorityBackground is for bulk and re-verification work that can
+	// wait.
+	PriorityBackground
+)
+
+// numPriorities is the number of Priority values.
+const numPriorities = 2
+
+func (p Priority) String() string {
+	if p == PriorityBackground {
+		return "background"
+	}
+	return "interactive"
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a copy of ctx that tags calls made with it as p.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFrom returns the priority ctx was tagged with, or
+// PriorityInteractive.
+func PriorityFrom(ctx context.Context) Priority {
+	p, _ := priorityFrom(ctx)
+	return p
+}
+
+func priorityFrom(ctx context.Context) (Priority, bool) {
+	p, ok := ctx.Value(priorityKey{}).(Priority)
+	if !ok || p < PriorityInteractive || p > PriorityBackground {
+		return PriorityInteractive, false
+	}
+	return p, true
+}
+
+// defaultPriority tags ctx as p unless the caller already chose a priority.
+func defaultPriority(ctx context.Context, p Priority) context.Context {
+	if _, ok := priorityFrom(ctx); ok {
+		return ctx
+	}
+	return WithPriority(ctx, p)
+}
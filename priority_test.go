@@ -0,0 +1,174 @@
+package sec4dev
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPriorityFrom(t *testing.T) {
+	ctx := context.Background()
+	if p := PriorityFrom(ctx); p != PriorityInteractive {
+		t.Errorf("default = %v, want interactive", p)
+	}
+	bg := WithPriority(ctx, PriorityBackground)
+	if p := PriorityFrom(bg); p != PriorityBackground {
+		t.Errorf("tagged = %v, want background", p)
+	}
+	if p := PriorityFrom(defaultPriority(WithPriority(ctx, PriorityInteractive), PriorityBackground)); p != PriorityInteractive {
+		t.Errorf("defaultPriority overrode the caller's choice: %v", p)
+	}
+}
+
+func TestLimiter_InteractiveJumpsQueue(t *testing.T) {
+	l := newLimiter(50, 1)
+	l.wait(context.Background())
+	bg := WithPriority(context.Background(), PriorityBackground)
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	waitAs := func(ctx context.Context, name string) {
+		defer wg.Done()
+		if err := l.wait(ctx); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		mu.Lock()
+		order = append(order, name)
+		mu.Unlock()
+	}
+	for _, name := range []string{"bg1", "bg2", "bg3"} {
+		wg.Add(1)
+		go waitAs(bg, name)
+	}
+	waitFor(t, func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.queued(PriorityBackground) == 3
+	})
+	wg.Add(1)
+	go waitAs(context.Background(), "interactive")
+	wg.Wait()
+	if order[0] != "interactive" {
+		t.Errorf("order = %v, want the interactive call first", order)
+	}
+}
+
+func TestLimiter_CancelledWaiterLeavesQueue(t *testing.T) {
+	l := newLimiter(20, 1)
+	l.wait(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- l.wait(ctx) }()
+	waitFor(t, func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.queued(PriorityBackground) == 1
+	})
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("err = %v, want Canceled", err)
+	}
+	start := time.Now()
+	if err := l.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 60*time.Millisecond {
+		t.Errorf("next wait took %v, want about one token interval", d)
+	}
+}
+
+func TestBulkhead_InteractiveFirstAndDisplacesBackground(t *testing.T) {
+	b := &bulkhead{max: 1, maxQueue: 2}
+	ctx := context.Background()
+	bg := WithPriority(ctx, PriorityBackground)
+	release, _ := b.acquire(ctx)
+
+	var mu sync.Mutex
+	var order []string
+	errs := make(map[string]error)
+	var wg sync.WaitGroup
+	start := func(ctx context.Context, name string, p Priority, want int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := b.acquire(ctx)
+			mu.Lock()
+			errs[name] = err
+			if err == nil {
+				order = append(order, name)
+			}
+			mu.Unlock()
+			if err == nil {
+				r()
+			}
+		}()
+		waitFor(t, func() bool {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			return len(b.queue[p]) == want
+		})
+	}
+	start(bg, "bg1", PriorityBackground, 1)
+	start(bg, "bg2", PriorityBackground, 2)
+	// The queue is full: the interactive call takes bg2's place.
+	start(ctx, "interactive", PriorityInteractive, 1)
+	release()
+	wg.Wait()
+
+	if !errors.Is(errs["bg2"], ErrOverloaded) {
+		t.Errorf("bg2 err = %v, want ErrOverloaded", errs["bg2"])
+	}
+	if len(order) != 2 || order[0] != "interactive" || order[1] != "bg1" {
+		t.Errorf("order = %v, want [interactive bg1]", order)
+	}
+	if _, err := b.acquire(bg); err != nil {
+		t.Errorf("slot leaked: %v", err)
+	}
+}
+
+func TestCheckBatch_RunsAtBackgroundPriority(t *testing.T) {
+	release := make(chan struct{})
+	var inFlight, peak int32
+	server := blockingServer(release, &inFlight, &peak)
+	defer server.Close()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(0), WithBulkhead(1, 1, 0))
+	ctx := context.Background()
+
+	first := make(chan error)
+	go func() {
+		_, err := client.Email().Check(ctx, "user@gmail.com")
+		first <- err
+	}()
+	waitFor(t, func() bool { return atomic.LoadInt32(&inFlight) == 1 })
+
+	batch := make(chan error)
+	go func() {
+		out, _ := client.Email().CheckBatch(ctx, []string{"other@gmail.com"})
+		batch <- out[0].Err
+	}()
+	waitFor(t, func() bool {
+		client.bulkhead.mu.Lock()
+		defer client.bulkhead.mu.Unlock()
+		return len(client.bulkhead.queue[PriorityBackground]) == 1
+	})
+
+	interactive := make(chan error)
+	go func() {
+		_, err := client.Email().Check(ctx, "login@gmail.com")
+		interactive <- err
+	}()
+	if err := <-batch; !errors.Is(err, ErrOverloaded) {
+		t.Errorf("batch err = %v, want ErrOverloaded", err)
+	}
+	close(release)
+	if err := <-first; err != nil {
+		t.Error(err)
+	}
+	if err := <-interactive; err != nil {
+		t.Errorf("interactive: %v", err)
+	}
+}
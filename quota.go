@@ -0,0 +1,270 @@
+package sec4dev
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultQuotaThresholds are the used-quota fractions that trigger alerts.
+var DefaultQuotaThresholds = []float64{0.8, 0.95}
+
+const maxQuotaSamples = 64
+
+// QuotaConfig configures a QuotaTracker.
+type QuotaConfig struct {
+	// Thresholds are fractions of the limit used (0..1) at which OnThreshold
+	// fires. Each fires once per rate limit window of each API key. Defaults
+	// to DefaultQuotaThresholds.
+	Thresholds []float64
+	// OnThreshold is called when usage crosses a threshold.
+	OnThreshold func(QuotaAlert)
+	// OnPaymentRequired is called the first time the API answers 402.
+	OnPaymentRequired func(*PaymentRequiredError)
+}
+
+// QuotaAlert describes a crossed usage threshold.
+type QuotaAlert struct {
+	// Key is the KeyPool name of the key whose quota crossed the threshold,
+	// or empty for a single API key.
+	Key       string
+	Threshold float64
+	Used      float64
+	RateLimit RateLimitInfo
+	Usage     QuotaUsage
+}
+
+// KeyQuota is the quota of one API key.
+type KeyQuota struct {
+	// RateLimit is the key's most recent rate limit header values.
+	RateLimit RateLimitInfo
+	// Used is the fraction of the current window's limit consumed.
+	Used float64
+	// ExhaustsAt is the projected time Remaining reaches zero at the observed
+	// consumption rate. It is zero if the quota is not projected to run out
+	// before the window resets.
+	ExhaustsAt time.Time
+	// ResetsAt is when the current window resets.
+	ResetsAt time.Time
+}
+
+// QuotaUsage is a snapshot of tracked usage. With a KeyPool the quota
+// fields cover the whole pool: RateLimit sums Limit and Remaining over the
+// keys, ResetsAt is the soonest reset, and ExhaustsAt is when the last key
+// is projected to run out. Keys has each key's own quota.
+type QuotaUsage struct {
+	// Calls counts responses received per endpoint path.
+	Calls map[string]int
+	Total int
+	// RateLimit is the most recent rate limit header values.
+	RateLimit RateLimitInfo
+	// Used is the fraction of the current window's limit consumed.
+	Used float64
+	// ExhaustsAt is the projected time Remaining reaches zero at the observed
+	// consumption rate. It is zero if the quota is not projected to run out
+	// before the window resets.
+	ExhaustsAt time.Time
+	// ResetsAt is when the current window resets.
+	ResetsAt time.Time
+	// Keys holds the quota per KeyPool key name; a single API key is
+	// under "".
+	Keys map[string]KeyQuota
+}
+
+// QuotaTracker accumulates API usage from rate limit headers and raises
+// budget alerts. It is safe for concurrent use.
+type QuotaTracker struct {
+	mu          sync.Mutex
+	cfg         QuotaConfig
+	calls       map[string]int
+	total       int
+	keys        map[string]*quotaWindow
+	paymentSeen bool
+}
+
+// quotaWindow tracks the rate limit window of one API key.
+type quotaWindow struct {
+	last    RateLimitInfo
+	lastAt  time.Time
+	samples []quotaSample
+	fired   map[float64]bool
+}
+
+type quotaSample struct {
+	at        time.Time
+	remaining int
+}
+
+// NewQuotaTracker creates a quota tracker.
+func NewQuotaTracker(cfg QuotaConfig) *QuotaTracker {
+	if len(cfg.Thresholds) == 0 {
+		cfg.Thresholds = DefaultQuotaThresholds
+	}
+	th := append([]float64(nil), cfg.Thresholds...)
+	sort.Float64s(th)
+	cfg.Thresholds = th
+	return &QuotaTracker{
+		cfg:   cfg,
+		calls: make(map[string]int),
+		keys:  make(map[string]*quotaWindow),
+	}
+}
+
+// WithQuotaTracker feeds rate limit headers and 402 responses into t.
+func WithQuotaTracker(t *QuotaTracker) ClientOption {
+	return func(c *Client) {
+		c.quota = t
+	}
+}
+
+// Usage returns a snapshot of tracked usage and the exhaustion projection.
+func (t *QuotaTracker) Usage() QuotaUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.usageLocked()
+}
+
+func (t *QuotaTracker) usageLocked() QuotaUsage {
+	u := QuotaUsage{
+		Calls: make(map[string]int, len(t.calls)),
+		Total: t.total,
+		Keys:  make(map[string]KeyQuota, len(t.keys)),
+	}
+	for k, v := range t.calls {
+		u.Calls[k] = v
+	}
+	limits := make(map[string]RateLimitInfo, len(t.keys))
+	allExhaust := len(t.keys) > 0
+	for name, w := range t.keys {
+		q := w.quota()
+		u.Keys[name] = q
+		limits[name] = q.RateLimit
+		if u.ResetsAt.IsZero() || q.ResetsAt.Before(u.ResetsAt) {
+			u.ResetsAt = q.ResetsAt
+		}
+		if q.ExhaustsAt.IsZero() {
+			allExhaust = false
+		} else if q.ExhaustsAt.After(u.ExhaustsAt) {
+			u.ExhaustsAt = q.ExhaustsAt
+		}
+	}
+	if !allExhaust {
+		u.ExhaustsAt = time.Time{}
+	}
+	u.RateLimit = sumRateLimits(limits)
+	u.Used = usedFraction(u.RateLimit)
+	return u
+}
+
+// quota projects the key's window from its samples.
+func (w *quotaWindow) quota() KeyQuota {
+	q := KeyQuota{RateLimit: w.last, Used: usedFraction(w.last)}
+	q.ResetsAt = w.lastAt.Add(time.Duration(w.last.ResetSeconds) * time.Second)
+	if len(w.samples) >= 2 {
+		first, last := w.samples[0], w.samples[len(w.samples)-1]
+		consumed := first.remaining - last.remaining
+		elapsed := last.at.Sub(first.at)
+		if consumed > 0 && elapsed > 0 {
+			perCall := elapsed / time.Duration(consumed)
+			at := last.at.Add(perCall * time.Duration(last.remaining))
+			if w.last.ResetSeconds == 0 || at.Before(q.ResetsAt) {
+				q.ExhaustsAt = at
+			}
+		}
+	}
+	return q
+}
+
+// sumRateLimits combines the rate limits of several keys: Limit and
+// Remaining are summed and ResetSeconds is the soonest reset.
+func sumRateLimits(limits map[string]RateLimitInfo) RateLimitInfo {
+	var sum RateLimitInfo
+	for _, rl := range limits {
+		sum.Limit += rl.Limit
+		sum.Remaining += rl.Remaining
+		if rl.ResetSeconds > 0 && (sum.ResetSeconds == 0 || rl.ResetSeconds < sum.ResetSeconds) {
+			sum.ResetSeconds = rl.ResetSeconds
+		}
+	}
+	return sum
+}
+
+func usedFraction(rl RateLimitInfo) float64 {
+	if rl.Limit <= 0 {
+		return 0
+	}
+	used := float64(rl.Limit-rl.Remaining) / float64(rl.Limit)
+	if used < 0 {
+		return 0
+	}
+	return used
+}
+
+// observe records a response from endpoint, made with the API key named
+// key, carrying rate limit headers.
+func (t *QuotaTracker) observe(endpoint, key string, rl RateLimitInfo) {
+	if t == nil {
+		return
+	}
+	now := time.Now()
+	var alerts []QuotaAlert
+	t.mu.Lock()
+	t.calls[endpoint]++
+	t.total++
+	if rl.Limit > 0 {
+		w := t.keys[key]
+		if w == nil {
+			w = &quotaWindow{fired: make(map[float64]bool)}
+			t.keys[key] = w
+		}
+		// A higher Remaining than before means a new window started.
+		if len(w.samples) > 0 && rl.Remaining > w.samples[len(w.samples)-1].remaining {
+			w.samples = w.samples[:0]
+			w.fired = make(map[float64]bool)
+		}
+		w.samples = append(w.samples, quotaSample{at: now, remaining: rl.Remaining})
+		if len(w.samples) > maxQuotaSamples {
+			w.samples = append(w.samples[:0], w.samples[len(w.samples)-maxQuotaSamples:]...)
+		}
+		w.last = rl
+		w.lastAt = now
+		used := usedFraction(rl)
+		for _, th := range t.cfg.Thresholds {
+			if used >= th && !w.fired[th] {
+				w.fired[th] = true
+				alerts = append(alerts, QuotaAlert{Key: key, Threshold: th, Used: used, RateLimit: rl})
+			}
+		}
+		if len(alerts) > 0 {
+			usage := t.usageLocked()
+			for i := range alerts {
+				alerts[i].Usage = usage
+			}
+		}
+	}
+	t.mu.Unlock()
+	if t.cfg.OnThreshold != nil {
+		for _, a := range alerts {
+			t.cfg.OnThreshold(a)
+		}
+	}
+}
+
+// observeError records a final call error, firing OnPaymentRequired on the
+// first 402.
+func (t *QuotaTracker) observeError(err error) {
+	if t == nil {
+		return
+	}
+	pe, ok := err.(*PaymentRequiredError)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	first := !t.paymentSeen
+	t.paymentSeen = true
+	t.mu.Unlock()
+	if first && t.cfg.OnPaymentRequired != nil {
+		t.cfg.OnPaymentRequired(pe)
+	}
+}
@@ -0,0 +1,146 @@
+package sec4dev
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestQuotaTracker_ThresholdsAndCalls(t *testing.T) {
+	remaining := 23
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remaining -= 2
+		w.Header().Set("X-RateLimit-Limit", "100")
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Reset", "3600")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"email": "user@gmail.com", "domain": "gmail.com", "is_disposable": false,
+		})
+	}))
+	defer server.Close()
+
+	var alerts []QuotaAlert
+	tracker := NewQuotaTracker(QuotaConfig{
+		OnThreshold: func(a QuotaAlert) { alerts = append(alerts, a) },
+	})
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL+"/api/v1"), WithHTTPClient(server.Client()),
+		WithQuotaTracker(tracker))
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if _, err := client.Email().Check(ctx, "user@gmail.com"); err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+	}
+
+	if len(alerts) != 1 || alerts[0].Threshold != 0.8 {
+		t.Fatalf("alerts = %+v, want one 0.8 alert", alerts)
+	}
+	u := tracker.Usage()
+	if u.Total != 3 || u.Calls["/email/check"] != 3 {
+		t.Errorf("calls = %+v", u.Calls)
+	}
+	if u.RateLimit.Remaining != 17 || u.Used != 0.83 {
+		t.Errorf("usage = %+v", u)
+	}
+	if u.ExhaustsAt.IsZero() || !u.ExhaustsAt.Before(u.ResetsAt) {
+		t.Errorf("ExhaustsAt = %v, ResetsAt = %v", u.ExhaustsAt, u.ResetsAt)
+	}
+}
+
+func TestQuotaTracker_NewWindowRearms(t *testing.T) {
+	var fired int
+	tracker := NewQuotaTracker(QuotaConfig{
+		Thresholds:  []float64{0.5},
+		OnThreshold: func(QuotaAlert) { fired++ },
+	})
+	tracker.observe("/ip/check", "", RateLimitInfo{Limit: 10, Remaining: 4})
+	tracker.observe("/ip/check", "", RateLimitInfo{Limit: 10, Remaining: 3})
+	tracker.observe("/ip/check", "", RateLimitInfo{Limit: 10, Remaining: 9})
+	tracker.observe("/ip/check", "", RateLimitInfo{Limit: 10, Remaining: 2})
+	if fired != 2 {
+		t.Errorf("fired = %d, want 2", fired)
+	}
+}
+
+func TestQuotaTracker_TracksPoolKeysSeparately(t *testing.T) {
+	var mu sync.Mutex
+	remaining := map[string]int{"sec4_a": 30, "sec4_b": 90}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		key := r.Header.Get("X-API-Key")
+		remaining[key]--
+		n := remaining[key]
+		mu.Unlock()
+		w.Header().Set("X-RateLimit-Limit", "100")
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(n))
+		w.Header().Set("X-RateLimit-Reset", "3600")
+		emailHandler(w, r)
+	}))
+	defer server.Close()
+
+	var alerts []QuotaAlert
+	tracker := NewQuotaTracker(QuotaConfig{
+		Thresholds:  []float64{0.5},
+		OnThreshold: func(a QuotaAlert) { alerts = append(alerts, a) },
+	})
+	pool, _ := NewKeyPool(KeyPoolConfig{Keys: []PoolKey{{Key: "sec4_a", Name: "a"}, {Key: "sec4_b", Name: "b"}}})
+	m := NewMetrics()
+	client, _ := NewClientWithCredentials(pool, WithBaseURL(server.URL), WithQuotaTracker(tracker), WithMetrics(m))
+	ctx := context.Background()
+	// Calls alternate between the keys at random; key b's higher Remaining
+	// must not look like a new window for key a.
+	for i := 0; i < 20; i++ {
+		if _, err := client.Email().Check(ctx, "user@gmail.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(alerts) != 1 || alerts[0].Key != "a" {
+		t.Errorf("alerts = %+v, want one for key a", alerts)
+	}
+	u := tracker.Usage()
+	mu.Lock()
+	want := RateLimitInfo{Limit: 200, Remaining: remaining["sec4_a"] + remaining["sec4_b"], ResetSeconds: 3600}
+	mu.Unlock()
+	if u.RateLimit != want || len(u.Keys) != 2 || u.Keys["a"].RateLimit.Limit != 100 {
+		t.Errorf("usage = %+v, want pool sum %+v", u, want)
+	}
+	if rl := client.RateLimit(); rl != want {
+		t.Errorf("RateLimit() = %+v, want %+v", rl, want)
+	}
+	var buf bytes.Buffer
+	m.WriteTo(&buf)
+	if !strings.Contains(buf.String(), `sec4dev_quota_limit{key="a"} 100`) || !strings.Contains(buf.String(), `sec4dev_quota_limit{key="b"} 100`) {
+		t.Errorf("metrics missing per-key quota:\n%s", buf.String())
+	}
+}
+
+func TestQuotaTracker_PaymentRequiredFiresOnce(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusPaymentRequired)
+		json.NewEncoder(w).Encode(map[string]string{"detail": "Quota exceeded"})
+	}))
+	defer server.Close()
+
+	var seen []*PaymentRequiredError
+	tracker := NewQuotaTracker(QuotaConfig{
+		OnPaymentRequired: func(e *PaymentRequiredError) { seen = append(seen, e) },
+	})
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL+"/api/v1"), WithHTTPClient(server.Client()),
+		WithQuotaTracker(tracker))
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := client.IP().Check(ctx, "203.0.113.42"); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	if len(seen) != 1 || seen[0].Message != "Quota exceeded" {
+		t.Errorf("seen = %+v", seen)
+	}
+}
@@ -0,0 +1,337 @@
+package sec4dev
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock records the timers asked of it. Each new timer advances the
+// clock to its own expiry, firing it and any earlier timers, unless hold is
+// set, in which case waiting is signalled instead and timers fire only when
+// advance moves the clock past them.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waits   []time.Duration
+	pending []*fakeTimer
+	hold    bool
+	waiting chan time.Duration
+}
+
+type fakeTimer struct {
+	clk *fakeClock
+	at  time.Time
+	c   chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Now(), waiting: make(chan time.Duration, 16)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.waits = append(f.waits, d)
+	t := &fakeTimer{clk: f, at: f.now.Add(d), c: make(chan time.Time, 1)}
+	f.pending = append(f.pending, t)
+	if f.hold {
+		f.waiting <- d
+		return t
+	}
+	f.fire(t.at)
+	return t
+}
+
+func (f *fakeClock) advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fire(f.now.Add(d))
+}
+
+// fire moves the clock to now and fires the timers due. The caller holds
+// f.mu.
+func (f *fakeClock) fire(now time.Time) {
+	f.now = now
+	kept := f.pending[:0]
+	for _, p := range f.pending {
+		if p.at.After(now) {
+			kept = append(kept, p)
+		} else {
+			p.c <- now
+		}
+	}
+	f.pending = kept
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.clk.mu.Lock()
+	defer t.clk.mu.Unlock()
+	for i, p := range t.clk.pending {
+		if p == t {
+			t.clk.pending = append(t.clk.pending[:i], t.clk.pending[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeClock) recorded() []time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]time.Duration(nil), f.waits...)
+}
+
+// zeroSource makes the backoff jitter always 0.
+type zeroSource struct{}
+
+func (zeroSource) Int63() int64 { return 0 }
+func (zeroSource) Seed(int64)   {}
+
+func statusServer(t *testing.T, calls *int32, fn func(n int32, w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fn(atomic.AddInt32(calls, 1), w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRetry_ServerErrorBackoff(t *testing.T) {
+	var calls int32
+	server := statusServer(t, &calls, func(n int32, w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	clk := newFakeClock()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(3), WithRetryDelay(100),
+		WithClock(clk), WithRandSource(zeroSource{}))
+
+	_, err := client.Email().Check(context.Background(), "user@gmail.com")
+	if _, ok := err.(*ServerError); !ok {
+		t.Fatalf("err = %T %v, want ServerError", err, err)
+	}
+	if calls != 4 {
+		t.Errorf("calls = %d, want 4", calls)
+	}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond}
+	if got := clk.recorded(); !reflect.DeepEqual(got, want) {
+		t.Errorf("waits = %v, want %v", got, want)
+	}
+}
+
+func TestRetry_RecoversAfterNetworkErrors(t *testing.T) {
+	var calls int32
+	server := statusServer(t, &calls, func(n int32, w http.ResponseWriter, r *http.Request) {
+		if n <= 2 {
+			// Drop the connection without a response.
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		emailHandler(w, r)
+	})
+	clk := newFakeClock()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(3), WithRetryDelay(50),
+		WithClock(clk), WithRandSource(zeroSource{}))
+
+	if _, err := client.Email().Check(context.Background(), "user@gmail.com"); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	want := []time.Duration{50 * time.Millisecond, 100 * time.Millisecond}
+	if got := clk.recorded(); calls != 3 || !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %d, waits = %v; want 3 and %v", calls, got, want)
+	}
+}
+
+func TestRetry_RateLimitExhausted(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		retryAfter string
+		wantWait   time.Duration
+		wantAfter  int
+	}{
+		{"header", "7", 7 * time.Second, 7},
+		{"default", "", 60 * time.Second, 60},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int32
+			server := statusServer(t, &calls, func(n int32, w http.ResponseWriter, r *http.Request) {
+				if tc.retryAfter != "" {
+					w.Header().Set("Retry-After", tc.retryAfter)
+				}
+				w.Header().Set("X-RateLimit-Limit", "100")
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte(`{"detail":"Rate limit exceeded"}`))
+			})
+			clk := newFakeClock()
+			client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(2), WithClock(clk))
+
+			_, err := client.Email().Check(context.Background(), "user@gmail.com")
+			var rl *RateLimitError
+			if !errors.As(err, &rl) || rl.RetryAfter != tc.wantAfter || rl.Limit != 100 || rl.Message != "Rate limit exceeded" {
+				t.Fatalf("err = %#v", err)
+			}
+			if calls != 3 {
+				t.Errorf("calls = %d, want 3", calls)
+			}
+			want := []time.Duration{tc.wantWait, tc.wantWait}
+			if got := clk.recorded(); !reflect.DeepEqual(got, want) {
+				t.Errorf("waits = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRetry_ContextCancelledDuringWait(t *testing.T) {
+	var calls int32
+	server := statusServer(t, &calls, func(n int32, w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	clk := newFakeClock()
+	clk.hold = true
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(3), WithClock(clk))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := client.Email().Check(ctx, "user@gmail.com")
+		done <- err
+	}()
+	<-clk.waiting
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want no attempt after the cancelled wait", calls)
+	}
+}
+
+func TestRetry_WaitPastDeadlineSkipped(t *testing.T) {
+	var calls int32
+	server := statusServer(t, &calls, func(n int32, w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	clk := newFakeClock()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(5), WithRetryDelay(1000),
+		WithClock(clk), WithRandSource(zeroSource{}))
+
+	// The deadline leaves room for the first 1s wait but not the second 2s
+	// one, which is skipped in favour of the error in hand.
+	ctx, cancel := context.WithDeadline(context.Background(), clk.Now().Add(1500*time.Millisecond))
+	defer cancel()
+	_, err := client.Email().Check(ctx, "user@gmail.com")
+	if _, ok := err.(*ServerError); !ok {
+		t.Fatalf("err = %T %v, want the ServerError in hand", err, err)
+	}
+	if got := clk.recorded(); calls != 2 || len(got) != 1 {
+		t.Errorf("calls = %d, waits = %v; want 2 calls and one wait", calls, got)
+	}
+}
+
+func TestRetry_NonRetryableNoWait(t *testing.T) {
+	var calls int32
+	server := statusServer(t, &calls, func(n int32, w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusPaymentRequired)
+	})
+	clk := newFakeClock()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(3), WithClock(clk))
+
+	_, err := client.Email().Check(context.Background(), "user@gmail.com")
+	if _, ok := err.(*PaymentRequiredError); !ok || calls != 1 || len(clk.recorded()) != 0 {
+		t.Errorf("err = %T, calls = %d, waits = %v", err, calls, clk.recorded())
+	}
+}
+
+func TestRetry_SeededJitterReproducible(t *testing.T) {
+	delays := func() []time.Duration {
+		client, _ := NewClient("sec4_test", WithRetryDelay(100), WithRandSource(rand.NewSource(42)))
+		var out []time.Duration
+		for attempt := 0; attempt < 4; attempt++ {
+			d := client.retryDelay(attempt)
+			base := time.Duration(100<<attempt) * time.Millisecond
+			if d < base || d > base+100*time.Millisecond {
+				t.Errorf("retryDelay(%d) = %v, want within 100ms above %v", attempt, d, base)
+			}
+			out = append(out, d)
+		}
+		return out
+	}
+	if a, b := delays(), delays(); !reflect.DeepEqual(a, b) {
+		t.Errorf("delays differ with the same seed: %v vs %v", a, b)
+	}
+}
+
+func TestRetry_OperationTimeoutUsesClock(t *testing.T) {
+	var calls int32
+	server := statusServer(t, &calls, func(n int32, w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	// Far from the real time, so mixing real and fake time would misjudge
+	// the deadline.
+	clk := newFakeClock()
+	clk.now = time.Date(2040, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk.hold = true
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(5), WithRetryDelay(1000),
+		WithOperationTimeout(1500*time.Millisecond), WithClock(clk), WithRandSource(zeroSource{}))
+
+	done := make(chan error)
+	go func() {
+		_, err := client.Email().Check(context.Background(), "user@gmail.com")
+		done <- err
+	}()
+	if d := <-clk.waiting; d != 1500*time.Millisecond {
+		t.Fatalf("first timer = %v, want the operation timeout", d)
+	}
+	if d := <-clk.waiting; d != time.Second {
+		t.Fatalf("second timer = %v, want the first backoff", d)
+	}
+	clk.advance(time.Second)
+	// The 2s backoff that follows cannot fit in the remaining 500ms.
+	if err := <-done; err == nil {
+		t.Fatal("Check succeeded")
+	} else if _, ok := err.(*ServerError); !ok {
+		t.Fatalf("err = %T %v, want the ServerError in hand", err, err)
+	}
+	if calls != 2 || len(clk.recorded()) != 2 {
+		t.Errorf("calls = %d, waits = %v; want 2 calls and no further wait", calls, clk.recorded())
+	}
+}
+
+func TestRetry_OperationTimeoutFiresOnClock(t *testing.T) {
+	clk := newFakeClock()
+	clk.hold = true
+	client, _ := NewClient("sec4_test", WithClock(clk))
+
+	ctx, cancel := client.withTimeout(context.Background(), time.Second)
+	defer cancel()
+	if d, ok := ctx.Deadline(); !ok || !d.Equal(clk.Now().Add(time.Second)) {
+		t.Errorf("deadline = %v, want one clock second away", d)
+	}
+	<-clk.waiting
+	clk.advance(time.Second)
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context did not expire on the fake clock")
+	}
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Errorf("err = %v, want DeadlineExceeded", ctx.Err())
+	}
+}
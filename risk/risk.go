@@ -0,0 +1,187 @@
+// Package risk combines Sec4Dev email and IP check results into a single
+// signup risk score with the reasons behind it.
+package risk
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sec4dev/sec4dev-go"
+)
+
+// Signal names one contributor to the score.
+type Signal string
+
+// Signals scored by the engine.
+const (
+	SignalTor               Signal = "tor"
+	SignalVPN               Signal = "vpn"
+	SignalProxy             Signal = "proxy"
+	SignalHosting           Signal = "hosting"
+	SignalDisposableEmail   Signal = "disposable_email"
+	SignalLowConfidence     Signal = "low_confidence"
+	SignalGeoMismatch       Signal = "geo_mismatch"
+	SignalCountryNotAllowed Signal = "country_not_allowed"
+	SignalDenylisted        Signal = "denylisted"
+)
+
+// Weights maps each signal to the points it adds when present. Signals
+// missing from the map add nothing.
+type Weights map[Signal]float64
+
+// DefaultWeights is used when Config.Weights is nil.
+var DefaultWeights = Weights{
+	SignalTor:               60,
+	SignalProxy:             40,
+	SignalVPN:               30,
+	SignalHosting:           25,
+	SignalDisposableEmail:   50,
+	SignalLowConfidence:     10,
+	SignalGeoMismatch:       20,
+	SignalCountryNotAllowed: 30,
+	SignalDenylisted:        100,
+}
+
+const (
+	defaultLowConfidence = 0.5
+	defaultMaxScore      = 100
+)
+
+// Config configures an Engine.
+type Config struct {
+	// Weights per signal. Defaults to DefaultWeights.
+	Weights Weights
+	// LowConfidence is the IP confidence below which SignalLowConfidence
+	// fires (default: 0.5).
+	LowConfidence float64
+	// MaxScore caps the score (default: 100). Scores never go below zero.
+	MaxScore float64
+}
+
+// Input is what one assessment is computed from. Email and IP may be nil
+// when that check was not run.
+type Input struct {
+	Email *sec4dev.EmailCheckResult
+	IP    *sec4dev.IPCheckResult
+	// AllowedCountries, if set, lists ISO country codes; an IP located
+	// elsewhere adds SignalCountryNotAllowed.
+	AllowedCountries []string
+	// ExpectedCountry, if set, is the country the user claims (e.g. from a
+	// billing address); an IP located elsewhere adds SignalGeoMismatch.
+	ExpectedCountry string
+}
+
+// Reason is one signal that contributed to a score.
+type Reason struct {
+	Signal Signal
+	Points float64
+	Detail string
+}
+
+// Assessment is a risk score and the reasons for it, highest points first.
+type Assessment struct {
+	Score   float64
+	Reasons []Reason
+}
+
+// Has reports whether signal contributed to the assessment.
+func (a Assessment) Has(signal Signal) bool {
+	for _, r := range a.Reasons {
+		if r.Signal == signal {
+			return true
+		}
+	}
+	return false
+}
+
+// Engine scores check results. It is safe for concurrent use.
+type Engine struct {
+	weights       Weights
+	lowConfidence float64
+	maxScore      float64
+}
+
+// New creates an engine.
+func New(cfg Config) *Engine {
+	e := &Engine{weights: cfg.Weights, lowConfidence: cfg.LowConfidence, maxScore: cfg.MaxScore}
+	if e.weights == nil {
+		e.weights = DefaultWeights
+	}
+	if e.lowConfidence == 0 {
+		e.lowConfidence = defaultLowConfidence
+	}
+	if e.maxScore == 0 {
+		e.maxScore = defaultMaxScore
+	}
+	return e
+}
+
+// Score computes the assessment for in.
+func (e *Engine) Score(in Input) Assessment {
+	var a Assessment
+	add := func(s Signal, detail string) {
+		if w := e.weights[s]; w != 0 {
+			a.Reasons = append(a.Reasons, Reason{Signal: s, Points: w, Detail: detail})
+			a.Score += w
+		}
+	}
+
+	if in.Email != nil && in.Email.IsDisposable {
+		add(SignalDisposableEmail, fmt.Sprintf("%s is a disposable email domain", in.Email.Domain))
+	}
+	if in.Email != nil && in.Email.Overridden == sec4dev.OverrideDeny {
+		add(SignalDenylisted, in.Email.Domain+" is on the deny list")
+	}
+	if ip := in.IP; ip != nil {
+		if ip.Overridden == sec4dev.OverrideDeny {
+			add(SignalDenylisted, ip.IP+" is on the deny list")
+		}
+		if ip.Signals.IsTor {
+			add(SignalTor, ip.IP+" is a Tor exit node")
+		}
+		if ip.Signals.IsProxy {
+			add(SignalProxy, ip.IP+" is a proxy")
+		}
+		if ip.Signals.IsVPN {
+			add(SignalVPN, ip.IP+" is a VPN endpoint")
+		}
+		if ip.Signals.IsHosting {
+			detail := ip.IP + " belongs to a hosting provider"
+			if ip.Network.Provider != "" {
+				detail += " (" + ip.Network.Provider + ")"
+			}
+			add(SignalHosting, detail)
+		}
+		if ip.Confidence < e.lowConfidence {
+			add(SignalLowConfidence, fmt.Sprintf("IP classification confidence %.2f is below %.2f", ip.Confidence, e.lowConfidence))
+		}
+		country := strings.ToUpper(ip.Geo.Country)
+		if country != "" {
+			if in.ExpectedCountry != "" && !strings.EqualFold(in.ExpectedCountry, country) {
+				add(SignalGeoMismatch, fmt.Sprintf("IP is in %s, expected %s", country, strings.ToUpper(in.ExpectedCountry)))
+			}
+			if len(in.AllowedCountries) > 0 && !containsFold(in.AllowedCountries, country) {
+				add(SignalCountryNotAllowed, fmt.Sprintf("IP country %s is not allowed", country))
+			}
+		}
+	}
+
+	sort.SliceStable(a.Reasons, func(i, j int) bool { return a.Reasons[i].Points > a.Reasons[j].Points })
+	if a.Score < 0 {
+		a.Score = 0
+	}
+	if a.Score > e.maxScore {
+		a.Score = e.maxScore
+	}
+	return a
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package risk
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sec4dev/sec4dev-go"
+)
+
+func TestScore_CombinesSignals(t *testing.T) {
+	e := New(Config{})
+	a := e.Score(Input{
+		Email: &sec4dev.EmailCheckResult{Email: "x@tempmail.com", Domain: "tempmail.com", IsDisposable: true},
+		IP: &sec4dev.IPCheckResult{
+			IP: "203.0.113.42", Confidence: 0.9,
+			Signals: sec4dev.IPSignals{IsHosting: true},
+			Network: sec4dev.IPNetwork{Provider: "AWS"},
+			Geo:     sec4dev.IPGeo{Country: "US"},
+		},
+	})
+	if a.Score != 75 {
+		t.Errorf("Score = %v, want 75", a.Score)
+	}
+	if len(a.Reasons) != 2 || a.Reasons[0].Signal != SignalDisposableEmail || a.Reasons[1].Signal != SignalHosting {
+		t.Errorf("Reasons = %+v", a.Reasons)
+	}
+}
+
+func TestScore_GeoAndConfidence(t *testing.T) {
+	e := New(Config{Weights: Weights{SignalGeoMismatch: 15, SignalCountryNotAllowed: 35, SignalLowConfidence: 5}})
+	a := e.Score(Input{
+		IP:               &sec4dev.IPCheckResult{IP: "198.51.100.7", Confidence: 0.2, Geo: sec4dev.IPGeo{Country: "br"}},
+		AllowedCountries: []string{"US", "CA"},
+		ExpectedCountry:  "us",
+	})
+	if a.Score != 55 {
+		t.Errorf("Score = %v, want 55", a.Score)
+	}
+	for _, s := range []Signal{SignalGeoMismatch, SignalCountryNotAllowed, SignalLowConfidence} {
+		if !a.Has(s) {
+			t.Errorf("missing %s in %+v", s, a.Reasons)
+		}
+	}
+}
+
+func TestScore_ClampsAndHandlesNil(t *testing.T) {
+	e := New(Config{})
+	if a := e.Score(Input{}); a.Score != 0 || len(a.Reasons) != 0 {
+		t.Errorf("empty input = %+v", a)
+	}
+	a := e.Score(Input{IP: &sec4dev.IPCheckResult{Confidence: 1, Signals: sec4dev.IPSignals{IsTor: true, IsProxy: true, IsVPN: true}}})
+	if a.Score != 100 {
+		t.Errorf("Score = %v, want clamped to 100", a.Score)
+	}
+}
+
+func TestScore_Denylisted(t *testing.T) {
+	o, err := sec4dev.NewOverrides(sec4dev.OverrideConfig{DenyIPs: []string{"198.51.100.0/24"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, _ := sec4dev.NewClient("sec4_test", sec4dev.WithBaseURL("http://127.0.0.1:0"), sec4dev.WithOverrides(o))
+	ip, err := client.IP().Check(context.Background(), "198.51.100.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := New(Config{}).Score(Input{IP: ip})
+	if a.Score != 100 || !a.Has(SignalDenylisted) {
+		t.Errorf("denied IP = %+v, want a blocking score", a)
+	}
+}
@@ -0,0 +1,118 @@
+package sec4dev
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SignupDecision is a policy's verdict on a signup. Action is typically
+// "allow", "challenge" or "deny"; Rule names the rule that decided, if any.
+type SignupDecision struct {
+	Action string
+	Rule   string
+}
+
+// SignupInput is what a SignupPolicy decides on.
+type SignupInput struct {
+	// Email and IP are nil when that check failed; EmailErr and IPErr say
+	// why.
+	Email    *EmailCheckResult
+	IP       *IPCheckResult
+	EmailErr error
+	IPErr    error
+	// ExpectedCountry and AllowedCountries are set with SignupOptions for
+	// geo rules and risk scoring.
+	ExpectedCountry  string
+	AllowedCountries []string
+}
+
+// SignupPolicy decides a signup from its check results. *policy.Policy
+// implements it.
+type SignupPolicy interface {
+	DecideSignup(in SignupInput) SignupDecision
+}
+
+// SignupOption sets per-signup context for AssessSignup.
+type SignupOption func(*SignupInput)
+
+// WithExpectedCountry sets the ISO country code the user claims, e.g. from a
+// billing address.
+func WithExpectedCountry(country string) SignupOption {
+	return func(in *SignupInput) {
+		in.ExpectedCountry = country
+	}
+}
+
+// WithAllowedCountries sets the ISO country codes signups may come from.
+func WithAllowedCountries(countries ...string) SignupOption {
+	return func(in *SignupInput) {
+		in.AllowedCountries = countries
+	}
+}
+
+// WithSignupPolicy sets the policy AssessSignup applies.
+func WithSignupPolicy(p SignupPolicy) ClientOption {
+	return func(c *Client) {
+		c.signupPolicy = p
+	}
+}
+
+// SignupVerdict is the outcome of AssessSignup.
+type SignupVerdict struct {
+	// Decision is the policy's verdict; it is zero when no policy is
+	// configured or both checks failed.
+	Decision SignupDecision
+	Email    *EmailCheckResult
+	IP       *IPCheckResult
+	// EmailErr and IPErr hold the error of a check that failed.
+	EmailErr error
+	IPErr    error
+	// Incomplete is set when one check failed, so Decision was made on the
+	// other result alone.
+	Incomplete bool
+	// EmailDuration and IPDuration time each check; Duration is the total.
+	EmailDuration time.Duration
+	IPDuration    time.Duration
+	Duration      time.Duration
+}
+
+// AssessSignup checks email and ip concurrently and applies the policy set
+// with WithSignupPolicy. If one check fails the policy still runs, with the
+// failure in its input (email.checked or ip.checked is false for a
+// *policy.Policy), and the verdict is marked Incomplete. The returned error
+// is non-nil only when both checks failed; the verdict is still returned
+// with both errors.
+func (c *Client) AssessSignup(ctx context.Context, email, ip string, opts ...SignupOption) (*SignupVerdict, error) {
+	start := time.Now()
+	v := &SignupVerdict{}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		t := time.Now()
+		v.Email, v.EmailErr = c.Email().Check(ctx, email)
+		v.EmailDuration = time.Since(t)
+	}()
+	go func() {
+		defer wg.Done()
+		t := time.Now()
+		v.IP, v.IPErr = c.IP().Check(ctx, ip)
+		v.IPDuration = time.Since(t)
+	}()
+	wg.Wait()
+	v.Duration = time.Since(start)
+
+	if v.EmailErr != nil && v.IPErr != nil {
+		return v, v.EmailErr
+	}
+	v.Incomplete = v.EmailErr != nil || v.IPErr != nil
+	if c.signupPolicy != nil {
+		in := SignupInput{Email: v.Email, IP: v.IP, EmailErr: v.EmailErr, IPErr: v.IPErr}
+		for _, opt := range opts {
+			opt(&in)
+		}
+		v.Decision = c.signupPolicy.DecideSignup(in)
+	}
+	return v, nil
+}
@@ -0,0 +1,96 @@
+package sec4dev
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type countryPolicy struct{}
+
+func (countryPolicy) DecideSignup(in SignupInput) SignupDecision {
+	if in.IPErr != nil {
+		return SignupDecision{Action: "challenge", Rule: "ip_unknown"}
+	}
+	want := in.ExpectedCountry
+	if want == "" {
+		want = "US"
+	}
+	if in.IP != nil && in.IP.Geo.Country != want {
+		return SignupDecision{Action: "challenge", Rule: "foreign"}
+	}
+	return SignupDecision{Action: "allow"}
+}
+
+func signupServer(delay time.Duration, failIP bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		if r.URL.Path == "/email/check" {
+			emailHandler(w, r)
+			return
+		}
+		if failIP {
+			w.WriteHeader(422)
+			w.Write([]byte(`{"detail": "bad ip"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ip": "203.0.113.7", "classification": "residential", "confidence": 0.9,
+			"geo": map[string]string{"country": "BR"},
+		})
+	}))
+}
+
+func TestAssessSignup_Concurrent(t *testing.T) {
+	server := signupServer(100*time.Millisecond, false)
+	defer server.Close()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(0), WithSignupPolicy(countryPolicy{}))
+
+	v, err := client.AssessSignup(context.Background(), "user@gmail.com", "203.0.113.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Email == nil || v.IP == nil || v.EmailErr != nil || v.IPErr != nil || v.Incomplete {
+		t.Fatalf("verdict = %+v", v)
+	}
+	if v.Decision != (SignupDecision{Action: "challenge", Rule: "foreign"}) {
+		t.Errorf("Decision = %+v", v.Decision)
+	}
+	if v.EmailDuration < 100*time.Millisecond || v.IPDuration < 100*time.Millisecond {
+		t.Errorf("durations = %v, %v", v.EmailDuration, v.IPDuration)
+	}
+	if v.Duration >= 200*time.Millisecond {
+		t.Errorf("Duration = %v, checks did not run concurrently", v.Duration)
+	}
+
+	v, _ = client.AssessSignup(context.Background(), "user@gmail.com", "203.0.113.7", WithExpectedCountry("BR"))
+	if v.Decision.Action != "allow" {
+		t.Errorf("with expected country BR: Decision = %+v", v.Decision)
+	}
+}
+
+func TestAssessSignup_PartialAndTotalFailure(t *testing.T) {
+	server := signupServer(0, true)
+	defer server.Close()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(0), WithSignupPolicy(countryPolicy{}))
+	ctx := context.Background()
+
+	v, err := client.AssessSignup(ctx, "user@gmail.com", "203.0.113.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v.IPErr.(*ValidationError); !ok || v.IP != nil || v.Email == nil || !v.Incomplete {
+		t.Fatalf("verdict = %+v", v)
+	}
+	if v.Decision != (SignupDecision{Action: "challenge", Rule: "ip_unknown"}) {
+		t.Errorf("Decision = %+v, want the policy to see the failed check", v.Decision)
+	}
+
+	v, err = client.AssessSignup(ctx, "not-an-email", "203.0.113.7")
+	if err == nil || v.EmailErr == nil || v.IPErr == nil || v.Decision.Action != "" {
+		t.Errorf("err = %v, verdict = %+v", err, v)
+	}
+}
@@ -0,0 +1,91 @@
+package sec4dev
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"time"
+)
+
+// WithStaleWhileRevalidate serves cached results up to maxStale past the
+// cache TTL immediately, marked Stale, and refreshes them in the background.
+// The cache TTL set with WithCache becomes the soft TTL; entries are kept
+// until the hard TTL, the soft TTL plus the larger stale window.
+func WithStaleWhileRevalidate(maxStale time.Duration) ClientOption {
+	return func(c *Client) {
+		c.staleWhileRevalidate = maxStale
+	}
+}
+
+// WithStaleIfError serves cached results up to maxStale past the cache TTL,
+// marked Stale, when the API fails with ServerError, RateLimitError or a
+// network error, or the bulkhead sheds the call. Calls that have such a
+// fallback are not retried.
+func WithStaleIfError(maxStale time.Duration) ClientOption {
+	return func(c *Client) {
+		c.staleIfError = maxStale
+	}
+}
+
+// hardTTL is how long cache entries are kept.
+func (c *Client) hardTTL() time.Duration {
+	stale := c.staleWhileRevalidate
+	if c.staleIfError > stale {
+		stale = c.staleIfError
+	}
+	return c.cacheTTL + stale
+}
+
+// markStale flags a decoded result as served stale.
+func markStale(result interface{}, age time.Duration) {
+	switch r := result.(type) {
+	case *EmailCheckResult:
+		r.Stale, r.Age = true, age
+	case *IPCheckResult:
+		r.Stale, r.Age = true, age
+	}
+}
+
+// canServeStale reports whether err is an API outage rather than a problem
+// with the request or the caller's context.
+func canServeStale(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	switch err.(type) {
+	case *ServerError, *RateLimitError, *OverloadedError:
+		return true
+	}
+	var urlErr *url.Error
+	var netErr net.Error
+	return errors.As(err, &urlErr) || errors.As(err, &netErr)
+}
+
+// revalidate refreshes req's cache entry in the background, at background
+// priority, unless a refresh is already running.
+func (c *Client) revalidate(ctx context.Context, req *Request, decode func([]byte) (interface{}, error)) {
+	key := cacheKey(req)
+	c.mu.Lock()
+	if c.refreshing[key] {
+		c.mu.Unlock()
+		return
+	}
+	if c.refreshing == nil {
+		c.refreshing = make(map[string]bool)
+	}
+	c.refreshing[key] = true
+	c.mu.Unlock()
+
+	r := *req
+	r.Header = req.Header.Clone()
+	r.refresh = true
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.refreshing, key)
+			c.mu.Unlock()
+		}()
+		c.send(WithPriority(context.WithoutCancel(ctx), PriorityBackground), &r, decode)
+	}()
+}
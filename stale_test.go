@@ -0,0 +1,118 @@
+package sec4dev
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// seedEmail caches a disposable result for email stored age ago.
+func seedEmail(cache Cache, email string, age time.Duration) {
+	stored := time.Now().Add(-age)
+	key := cacheKey(&Request{Path: "/email/check", Body: map[string]string{"email": email}})
+	cache.Set(key, CacheEntry{
+		Value:     []byte(`{"email":"` + email + `","domain":"gmail.com","is_disposable":true}`),
+		StoredAt:  stored,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		emailHandler(w, r)
+	}))
+	defer server.Close()
+	cache := NewMemoryCache(0)
+	m := NewMetrics()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithMetrics(m),
+		WithCache(cache, time.Minute), WithStaleWhileRevalidate(time.Minute))
+	seedEmail(cache, "user@gmail.com", 90*time.Second)
+	ctx := context.Background()
+
+	r, err := client.Email().Check(ctx, "user@gmail.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Stale || !r.IsDisposable || r.Age < 90*time.Second || r.Age > 100*time.Second {
+		t.Fatalf("result = %+v, want the stale cached one", r)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		r, err = client.Email().Check(ctx, "user@gmail.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !r.Stale {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("entry was not refreshed in the background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if r.IsDisposable || r.Age != 0 {
+		t.Errorf("refreshed result = %+v", r)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("API calls = %d, want 1 background refresh", n)
+	}
+}
+
+func TestStaleIfError(t *testing.T) {
+	var calls int32
+	status := int32(503)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if s := int(atomic.LoadInt32(&status)); s == 429 {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(s)
+			w.Write([]byte(`{"detail": "slow down"}`))
+			return
+		} else if s != 200 {
+			w.WriteHeader(s)
+			w.Write([]byte(`{"detail": "down"}`))
+			return
+		}
+		emailHandler(w, r)
+	}))
+	cache := NewMemoryCache(0)
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(3), WithRetryDelay(1),
+		WithCache(cache, time.Minute), WithStaleIfError(time.Hour))
+	ctx := context.Background()
+
+	for _, s := range []int32{503, 429} {
+		atomic.StoreInt32(&status, s)
+		atomic.StoreInt32(&calls, 0)
+		seedEmail(cache, "user@gmail.com", 2*time.Minute)
+		r, err := client.Email().Check(ctx, "user@gmail.com")
+		if err != nil || !r.Stale || !r.IsDisposable {
+			t.Fatalf("%d: result = %+v, err = %v", s, r, err)
+		}
+		if n := atomic.LoadInt32(&calls); n != 1 {
+			t.Errorf("%d: API calls = %d, want 1 without retries", s, n)
+		}
+	}
+
+	atomic.StoreInt32(&status, 422)
+	if _, err := client.Email().Check(ctx, "user@gmail.com"); err == nil {
+		t.Error("validation error hidden by a stale result")
+	}
+
+	atomic.StoreInt32(&status, 503)
+	seedEmail(cache, "user@gmail.com", 2*time.Hour)
+	if _, err := client.Email().Check(ctx, "user@gmail.com"); err == nil {
+		t.Error("served a result past the stale window")
+	}
+
+	server.Close()
+	seedEmail(cache, "user@gmail.com", 2*time.Minute)
+	if r, err := client.Email().Check(ctx, "user@gmail.com"); err != nil || !r.Stale {
+		t.Errorf("network error: result = %+v, err = %v", r, err)
+	}
+}
@@ -0,0 +1,123 @@
+package sec4dev
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAttemptTimeout_RetriesHungAttempt(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			io.Copy(io.Discard, r.Body)
+			<-r.Context().Done()
+			return
+		}
+		emailHandler(w, r)
+	}))
+	defer server.Close()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(1), WithRetryDelay(1),
+		WithAttemptTimeout(50*time.Millisecond))
+
+	start := time.Now()
+	if _, err := client.Email().Check(context.Background(), "user@gmail.com"); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("took %v, want the hung attempt cut short", d)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("calls = %d, want 2", n)
+	}
+}
+
+func TestOperationTimeout_BoundsRetries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(10), WithRetryDelay(40),
+		WithOperationTimeout(150*time.Millisecond), WithRandSource(zeroSource{}))
+
+	start := time.Now()
+	_, err := client.Email().Check(context.Background(), "user@gmail.com")
+	if _, ok := err.(*ServerError); !ok {
+		t.Fatalf("err = %T %v, want the last ServerError", err, err)
+	}
+	if d := time.Since(start); d > 150*time.Millisecond {
+		t.Errorf("took %v, want at most the operation timeout", d)
+	}
+	if n := atomic.LoadInt32(&calls); n < 2 || n > 3 {
+		t.Errorf("calls = %d, want the retries that fit", n)
+	}
+}
+
+func TestOperationTimeout_SpansKeyFailover(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(80 * time.Millisecond)
+		w.WriteHeader(http.StatusPaymentRequired)
+		w.Write([]byte(`{"detail":"Quota exceeded"}`))
+	}))
+	defer server.Close()
+	pool, _ := NewKeyPool(KeyPoolConfig{Keys: []PoolKey{{Key: "sec4_a"}, {Key: "sec4_b"}, {Key: "sec4_c"}}})
+	client, _ := NewClientWithCredentials(pool, WithBaseURL(server.URL), WithOperationTimeout(150*time.Millisecond))
+
+	start := time.Now()
+	_, err := client.Email().Check(context.Background(), "user@gmail.com")
+	if err == nil {
+		t.Fatal("Check succeeded")
+	}
+	// Each key would take 80ms; the second is cut short instead of every
+	// key getting a fresh budget.
+	if d := time.Since(start); d > 220*time.Millisecond {
+		t.Errorf("took %v, want at most the operation timeout across keys", d)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("calls = %d, want 2", n)
+	}
+}
+
+func TestRetry_SkipsWaitPastCallerDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(3))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := client.Email().Check(ctx, "user@gmail.com")
+	if rl, ok := err.(*RateLimitError); !ok || rl.RetryAfter != 60 {
+		t.Fatalf("err = %T %v, want RateLimitError", err, err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("took %v, want no wait that cannot finish", d)
+	}
+}
+
+func TestConfig_Timeouts(t *testing.T) {
+	t.Setenv(EnvAttemptTimeoutMs, "250")
+	t.Setenv(EnvOperationTimeoutMs, "2000")
+	cfg := &Config{APIKey: "sec4_test"}
+	if err := cfg.LoadEnv(); err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClientFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.attemptTimeout != 250*time.Millisecond || client.operationTimeout != 2*time.Second {
+		t.Errorf("timeouts = %v, %v", client.attemptTimeout, client.operationTimeout)
+	}
+}
@@ -0,0 +1,131 @@
+package sec4dev
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"time"
+)
+
+// TransportConfig tunes the transport of the default HTTP client. Zero
+// fields keep their defaults.
+type TransportConfig struct {
+	// DialTimeout bounds establishing a TCP connection (default: 10s).
+	DialTimeout time.Duration
+	// KeepAlive is the TCP keep-alive period (default: 30s).
+	KeepAlive time.Duration
+	// TLSHandshakeTimeout bounds the TLS handshake (default: 10s).
+	TLSHandshakeTimeout time.Duration
+	// ResponseHeaderTimeout bounds the wait for response headers after the
+	// request is written (default: 30s).
+	ResponseHeaderTimeout time.Duration
+	// IdleConnTimeout closes pooled connections idle this long (default: 90s).
+	IdleConnTimeout time.Duration
+	// MaxIdleConns caps idle connections across hosts (default: 100).
+	MaxIdleConns int
+	// MaxIdleConnsPerHost caps idle connections per host (default: 32).
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost caps all connections per host (default: unlimited).
+	MaxConnsPerHost int
+	// DisableHTTP2 turns off HTTP/2 negotiation.
+	DisableHTTP2 bool
+}
+
+// DefaultTransportConfig returns the transport settings used by default.
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		DialTimeout:           connectTimeout,
+		KeepAlive:             30 * time.Second,
+		TLSHandshakeTimeout:   connectTimeout,
+		ResponseHeaderTimeout: readTimeout,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   32,
+	}
+}
+
+// WithTransportConfig tunes the default HTTP client's transport. It has no
+// effect when WithHTTPClient is used.
+func WithTransportConfig(tc TransportConfig) ClientOption {
+	return func(c *Client) {
+		c.transportConfig = tc
+	}
+}
+
+// WithRootCAs trusts only the CAs in pool for TLS, e.g. a corporate CA used
+// by an egress proxy. It has no effect when WithHTTPClient is used.
+func WithRootCAs(pool *x509.CertPool) ClientOption {
+	return func(c *Client) {
+		c.rootCAs = pool
+	}
+}
+
+// WithClientCertificate presents cert for mutual TLS. It has no effect when
+// WithHTTPClient is used.
+func WithClientCertificate(cert tls.Certificate) ClientOption {
+	return func(c *Client) {
+		c.clientCerts = append(c.clientCerts, cert)
+	}
+}
+
+// httpClient returns the configured HTTP client, or the shared default
+// client built from the transport options.
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	c.defaultHTTPOnce.Do(func() {
+		// newClient sets the default timeout, so 0 here was asked for.
+		c.defaultHTTP = &http.Client{Timeout: c.timeout, Transport: c.newTransport()}
+	})
+	return c.defaultHTTP
+}
+
+func (c *Client) newTransport() *http.Transport {
+	tc := c.transportConfig
+	def := DefaultTransportConfig()
+	if tc.DialTimeout == 0 {
+		tc.DialTimeout = def.DialTimeout
+	}
+	if tc.KeepAlive == 0 {
+		tc.KeepAlive = def.KeepAlive
+	}
+	if tc.TLSHandshakeTimeout == 0 {
+		tc.TLSHandshakeTimeout = def.TLSHandshakeTimeout
+	}
+	if tc.ResponseHeaderTimeout == 0 {
+		tc.ResponseHeaderTimeout = def.ResponseHeaderTimeout
+	}
+	if tc.IdleConnTimeout == 0 {
+		tc.IdleConnTimeout = def.IdleConnTimeout
+	}
+	if tc.MaxIdleConns == 0 {
+		tc.MaxIdleConns = def.MaxIdleConns
+	}
+	if tc.MaxIdleConnsPerHost == 0 {
+		tc.MaxIdleConnsPerHost = def.MaxIdleConnsPerHost
+	}
+
+	dialer := &net.Dialer{Timeout: tc.DialTimeout, KeepAlive: tc.KeepAlive}
+	t := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: c.rootCAs, Certificates: c.clientCerts},
+		TLSHandshakeTimeout:   tc.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: tc.ResponseHeaderTimeout,
+		IdleConnTimeout:       tc.IdleConnTimeout,
+		MaxIdleConns:          tc.MaxIdleConns,
+		MaxIdleConnsPerHost:   tc.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       tc.MaxConnsPerHost,
+		ExpectContinueTimeout: time.Second,
+		ForceAttemptHTTP2:     !tc.DisableHTTP2,
+	}
+	if tc.DisableHTTP2 {
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	if c.proxyURL != nil {
+		t.Proxy = http.ProxyURL(c.proxyURL)
+	}
+	return t
+}
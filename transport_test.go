@@ -0,0 +1,81 @@
+package sec4dev
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestTransport_RootCAsAndClientCertificate(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(emailHandler))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	ctx := context.Background()
+
+	client, _ := NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(0), WithRootCAs(pool))
+	if _, err := client.Email().Check(ctx, "user@gmail.com"); err == nil {
+		t.Fatal("expected handshake failure without a client certificate")
+	}
+
+	client, _ = NewClient("sec4_test", WithBaseURL(server.URL), WithRetries(0), WithRootCAs(pool),
+		WithClientCertificate(server.TLS.Certificates[0]))
+	if _, err := client.Email().Check(ctx, "user@gmail.com"); err != nil {
+		t.Fatalf("Check with mTLS: %v", err)
+	}
+	if client.httpClient() != client.httpClient() {
+		t.Error("expected the default HTTP client to be shared across calls")
+	}
+}
+
+func TestTransport_Proxy(t *testing.T) {
+	var target string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target = r.URL.String()
+		emailHandler(w, r)
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	client, _ := NewClient("sec4_test", WithBaseURL("http://api.sec4.example/api/v1"), WithRetries(0), WithProxy(proxyURL))
+	if _, err := client.Email().Check(context.Background(), "user@gmail.com"); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if target != "http://api.sec4.example/api/v1/email/check" {
+		t.Errorf("proxy saw %q", target)
+	}
+}
+
+func TestTransport_Defaults(t *testing.T) {
+	client, _ := NewClient("sec4_test", WithTransportConfig(TransportConfig{MaxIdleConnsPerHost: 8, DisableHTTP2: true}))
+	tr := client.httpClient().Transport.(*http.Transport)
+	if tr.MaxIdleConnsPerHost != 8 || tr.ResponseHeaderTimeout != readTimeout || tr.TLSHandshakeTimeout != connectTimeout {
+		t.Errorf("transport = %+v", tr)
+	}
+	if tr.ForceAttemptHTTP2 {
+		t.Error("expected HTTP/2 disabled")
+	}
+}
+
+func TestTransport_ZeroTimeoutDisables(t *testing.T) {
+	client, _ := NewClient("sec4_test")
+	if d := client.httpClient().Timeout; d != connectTimeout+readTimeout {
+		t.Errorf("default timeout = %v", d)
+	}
+	t.Setenv(EnvAPIKey, "sec4_env")
+	t.Setenv(EnvTimeoutMs, "0")
+	client, err := NewClientFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := client.httpClient().Timeout; d != 0 {
+		t.Errorf("timeout = %v, want none with %s=0", d, EnvTimeoutMs)
+	}
+}